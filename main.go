@@ -4,8 +4,12 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
+	"path/filepath"
 
 	"github.com/containifyci/engine-ci/cmd"
+	"github.com/containifyci/engine-ci/pkg/build"
+	"github.com/containifyci/engine-ci/pkg/cri/utils"
+	"github.com/containifyci/engine-java/pkg/gradle"
 	"github.com/containifyci/engine-java/pkg/maven"
 	"github.com/spf13/cobra"
 )
@@ -60,6 +64,13 @@ func main() {
 			if err != nil {
 				return err
 			}
+			bs.AddToCategory(build.PreBuild, maven.NewFormat())
+			bs.AddToCategory(build.Build, maven.NewLint())
+			bs.AddToCategory(build.PostBuild, maven.NewPublish())
+
+			// Gradle projects share the Maven build type and are picked up by their build files
+			bs.AddToCategory(build.Build, gradle.New())
+			bs.AddToCategory(build.PostBuild, gradle.NewProd())
 
 			// Set version info (optional but recommended)
 			cmd.SetVersionInfo(version, commit, date, repo)
@@ -68,6 +79,8 @@ func main() {
 		command.Short = "engine-java (overridden)"
 	}
 
+	cmd.RootCmd().AddCommand(mvndStopCmd())
+
 	err = cmd.Execute()
 	if err != nil {
 		slog.Error("Main Error", "error", err)
@@ -76,3 +89,33 @@ func main() {
 
 	slog.Info("Version", "version", v)
 }
+
+// mvndStopCmd stops the long-lived builder containers the maven step keeps
+// running with mvnd.
+func mvndStopCmd() *cobra.Command {
+	var runtime string
+	var all bool
+	command := &cobra.Command{
+		Use:   "mvnd-stop",
+		Short: "Stop the mvnd builder container of the project",
+		RunE: func(command *cobra.Command, args []string) error {
+			project := ""
+			if !all {
+				dir, err := filepath.Abs(".")
+				if err != nil {
+					return err
+				}
+				project = dir
+			}
+			stopped, err := maven.StopDaemons(utils.RuntimeType(runtime), maven.DaemonStates(), project)
+			if err != nil {
+				return err
+			}
+			slog.Info("Stopped mvnd builder containers", "containers", stopped)
+			return nil
+		},
+	}
+	command.Flags().StringVar(&runtime, "runtime", "docker", "Container runtime, docker or podman")
+	command.Flags().BoolVar(&all, "all", false, "Stop the builder containers of all projects")
+	return command
+}
@@ -0,0 +1,30 @@
+// Package buildtool tells Maven and Gradle projects apart. Both are built for
+// the Maven build type of engine-ci, by the maven and the gradle steps.
+package buildtool
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/containifyci/engine-ci/pkg/container"
+)
+
+// GradleBuildFiles are the files marking a folder as a Gradle project.
+var GradleBuildFiles = []string{"build.gradle", "build.gradle.kts", "settings.gradle", "settings.gradle.kts"}
+
+// IsGradle reports whether the build is a Gradle project, either because the
+// build_tool property says so or because a Gradle build file exists in Folder.
+func IsGradle(build container.Build) bool {
+	if build.BuildType != container.Maven {
+		return false
+	}
+	if tool := build.Custom.String("build_tool"); tool != "" {
+		return tool == "gradle"
+	}
+	for _, file := range GradleBuildFiles {
+		if _, err := os.Stat(filepath.Join(build.Folder, file)); err == nil {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,29 @@
+package buildtool
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/containifyci/engine-ci/pkg/container"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsGradle(t *testing.T) {
+	build := container.Build{BuildType: container.Maven, Folder: t.TempDir(), Custom: container.Custom{}}
+	assert.False(t, IsGradle(build))
+
+	require.NoError(t, os.WriteFile(filepath.Join(build.Folder, "settings.gradle.kts"), []byte{}, 0o644))
+	assert.True(t, IsGradle(build))
+
+	build.Custom["build_tool"] = []string{"maven"}
+	assert.False(t, IsGradle(build))
+
+	build.Custom["build_tool"] = []string{"gradle"}
+	build.Folder = t.TempDir()
+	assert.True(t, IsGradle(build))
+
+	build.BuildType = container.GoLang
+	assert.False(t, IsGradle(build))
+}
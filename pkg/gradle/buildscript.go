@@ -0,0 +1,45 @@
+package gradle
+
+import "fmt"
+
+type BuildScript struct {
+	Verbose bool
+	Folder  string
+	Host    string
+}
+
+func NewBuildScript(verbose bool, folder, host string) *BuildScript {
+	return &BuildScript{
+		Verbose: verbose,
+		Folder:  folder,
+		Host:    host,
+	}
+}
+
+func Script(bs *BuildScript) string {
+	if bs.Verbose {
+		return verboseScript(bs)
+	}
+	return simpleScript(bs)
+}
+
+// gradleCmd prefers the project's wrapper and falls back to the gradle installed in the builder image.
+const gradleCmd = `if [ -f ./gradlew ]; then GRADLE="sh ./gradlew"; else GRADLE=gradle; fi`
+
+func simpleScript(bs *BuildScript) string {
+	return fmt.Sprintf(`#!/bin/sh
+set -xe
+cd %s
+%s
+$GRADLE --no-daemon --console=plain build
+`, bs.Folder, gradleCmd)
+}
+
+func verboseScript(bs *BuildScript) string {
+	return fmt.Sprintf(`#!/bin/sh
+set -xe
+cd %s
+%s
+$GRADLE --no-daemon --console=plain build --info
+`, bs.Folder, gradleCmd)
+}
@@ -0,0 +1,21 @@
+package gradle
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSimpleScript(t *testing.T) {
+	bs := NewBuildScript(false, ".", "localhost")
+	script := Script(bs)
+
+	assert.Equal(t, "#!/bin/sh\nset -xe\ncd .\nif [ -f ./gradlew ]; then GRADLE=\"sh ./gradlew\"; else GRADLE=gradle; fi\n$GRADLE --no-daemon --console=plain build\n", script)
+}
+
+func TestVerboseScript(t *testing.T) {
+	bs := NewBuildScript(true, "java", "localhost")
+	script := Script(bs)
+
+	assert.Equal(t, "#!/bin/sh\nset -xe\ncd java\nif [ -f ./gradlew ]; then GRADLE=\"sh ./gradlew\"; else GRADLE=gradle; fi\n$GRADLE --no-daemon --console=plain build --info\n", script)
+}
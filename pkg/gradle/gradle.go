@@ -0,0 +1,349 @@
+package gradle
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/containifyci/engine-ci/pkg/build"
+	"github.com/containifyci/engine-ci/pkg/container"
+	"github.com/containifyci/engine-ci/pkg/cri/types"
+	"github.com/containifyci/engine-ci/pkg/cri/utils"
+
+	"github.com/containifyci/engine-ci/pkg/network"
+	u "github.com/containifyci/engine-ci/pkg/utils"
+	"github.com/containifyci/engine-java/pkg/buildtool"
+	"github.com/containifyci/engine-java/pkg/maven"
+)
+
+const (
+	CacheLocation               = "/root/.gradle/"
+	DEFAULT_GRADLE_DIST_VERSION = "8.14.3"
+)
+
+// BuildFiles are the files marking a folder as a Gradle project.
+var BuildFiles = buildtool.GradleBuildFiles
+
+//go:embed Dockerfile.*
+var f embed.FS
+
+var dockerfileTemplate = template.Must(template.ParseFS(f, "Dockerfile.gradle.tmpl"))
+
+var warPlugin = regexp.MustCompile(`\bid\s*\(?\s*["']war["']|apply\s*\(?\s*plugin\s*[:=]\s*["']war["']|\bwar\s*\n`)
+
+type GradleContainer struct {
+	App       string
+	File      u.SrcFile
+	Folder    string
+	Image     string
+	ImageTag  string
+	Platform  types.Platform
+	ProdImage string
+
+	Version string
+	*container.Container
+}
+
+func New() build.BuildStep {
+	return build.Stepper{
+		RunFn: func(build container.Build) (string, error) {
+			container := new(&build)
+			return container.Run()
+		},
+		MatchedFn: Matches,
+		ImagesFn:  Images,
+		Name_:     "gradle",
+		Async_:    false,
+	}
+}
+
+// IsProject reports whether the build is a Gradle project, either because the
+// build_tool property says so or because a Gradle build file exists in Folder.
+func IsProject(build container.Build) bool {
+	return buildtool.IsGradle(build)
+}
+
+// Matches selects Gradle projects on a JDK the distribution of the jdk
+// property provides, the JDK is chosen as for maven projects.
+func Matches(build container.Build) bool {
+	if !IsProject(build) {
+		return false
+	}
+	jdk, err := maven.GetJDK(build)
+	if err != nil {
+		slog.Warn("Not building with gradle", "error", err)
+		return false
+	}
+	return jdk.Supports(maven.GetVersion(build))
+}
+
+func new(build *container.Build) *GradleContainer {
+	return &GradleContainer{
+		App:       build.App,
+		Container: container.New(*build),
+		Image:     build.Image,
+		Folder:    build.Folder,
+		File:      u.SrcFile(build.File),
+		ImageTag:  build.ImageTag,
+		Platform:  build.Platform,
+		ProdImage: ProdImage(*build),
+		Version:   maven.GetVersion(*build),
+	}
+}
+
+func (c *GradleContainer) IsAsync() bool {
+	return false
+}
+
+func (c *GradleContainer) Name() string {
+	return "gradle"
+}
+
+// CacheFolder returns the Gradle user home mounted into the build container,
+// GRADLE_USER_HOME or a gradle folder in CONTAINIFYCI_CACHE, which the maven
+// repository shares, or ~/.gradle.
+func CacheFolder() string {
+	if gradleHome := u.GetEnv("GRADLE_USER_HOME", "build"); gradleHome != "" {
+		return gradleHome
+	}
+	if cache := u.GetEnv("CONTAINIFYCI_CACHE", "build"); cache != "" {
+		return maven.EnsureCacheFolder(filepath.Join(cache, "gradle"))
+	}
+	gradleHome := maven.HomeCacheFolder(".gradle")
+	slog.Info("GRADLE_USER_HOME not set, using default", "gradleHome", gradleHome)
+	return gradleHome
+}
+
+func (c *GradleContainer) Pull() error {
+	return c.Container.Pull(c.ProdImage)
+}
+
+func Images(build container.Build) []string {
+	return []string{GradleImage(build), ProdImage(build)}
+}
+
+// ProdImage returns the base image of the prod image, chosen as for maven
+// projects: the JRE of the builder JDK for jars and the app server for wars.
+func ProdImage(build container.Build) string {
+	if prodImage := build.Custom.String("image"); prodImage != "" {
+		return prodImage
+	}
+	if ArtifactType(build) == maven.ArtifactJar {
+		args, err := maven.NewDockerfileArgs(build)
+		if err != nil {
+			slog.Error("Failed to select jre image", "error", err)
+			os.Exit(1)
+		}
+		jdk, _ := maven.GetJDK(build)
+		return jdk.RuntimeImage(args.JDK, args.Distro)
+	}
+	server, err := maven.GetAppServer(build)
+	if err != nil {
+		slog.Error("Failed to select app server", "error", err)
+		os.Exit(1)
+	}
+	return server.Image
+}
+
+// ArtifactType returns war for projects shipping a war and jar otherwise. It is
+// read from the artifact, or before the build from the war plugin in the build
+// files.
+func ArtifactType(build container.Build) string {
+	if file, err := Artifact(build); err == nil {
+		if strings.EqualFold(filepath.Ext(file), ".war") {
+			return maven.ArtifactWar
+		}
+		return maven.ArtifactJar
+	}
+	for _, file := range BuildFiles {
+		data, err := os.ReadFile(filepath.Join(build.Folder, file))
+		if err != nil {
+			continue
+		}
+		if warPlugin.Match(data) {
+			return maven.ArtifactWar
+		}
+	}
+	return maven.ArtifactJar
+}
+
+// DockerfileArgs parameterize the builder Dockerfile template. The JDK base
+// image is selected as for the maven builder.
+type DockerfileArgs struct {
+	maven.DockerfileArgs
+	GradleVersion string
+}
+
+// Dockerfile renders the builder Dockerfile for the build.
+func Dockerfile(build container.Build) ([]byte, error) {
+	args, err := maven.NewDockerfileArgs(build)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := dockerfileTemplate.Execute(&buf, DockerfileArgs{DockerfileArgs: args, GradleVersion: DEFAULT_GRADLE_DIST_VERSION}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func GradleImage(build container.Build) string {
+	dockerFile, err := Dockerfile(build)
+	if err != nil {
+		slog.Error("Failed to render Dockerfile.gradle", "error", err)
+		os.Exit(1)
+	}
+	jdk, err := maven.GetJDK(build)
+	if err != nil {
+		slog.Error("Failed to select jdk distribution", "error", err)
+		os.Exit(1)
+	}
+	tag := maven.ComputeChecksum(dockerFile)
+	image := fmt.Sprintf("gradle-%s-%s-%s", DEFAULT_GRADLE_DIST_VERSION, jdk.Vendor, maven.GetVersion(build))
+	return utils.ImageURI(build.ContainifyRegistry, image, tag)
+}
+
+func (c *GradleContainer) BuildGradleImage() error {
+	image := GradleImage(*c.GetBuild())
+	slog.Debug("Building gradle image", "image", image, "version", c.Version)
+	dockerFile, err := Dockerfile(*c.GetBuild())
+	if err != nil {
+		slog.Error("Failed to render Dockerfile.gradle", "error", err)
+		os.Exit(1)
+	}
+
+	platforms := types.GetPlatforms(c.GetBuild().Platform)
+	slog.Info("Building intermediate image", "image", image, "platforms", platforms)
+
+	err = c.BuildIntermidiateContainer(image, dockerFile, platforms...)
+	if err != nil {
+		slog.Error("Failed to build gradle image", "error", err)
+		os.Exit(1)
+	}
+	return nil
+}
+
+func (c *GradleContainer) Address() *network.Address {
+	return &network.Address{Host: "localhost"}
+}
+
+// BuildOpts returns the configuration of a container running gradle in the
+// builder image, with the limits of the maven resources. GRADLE_OPTS sizes the
+// heap like MAVEN_OPTS unless gradle_opts is set, gradle runs without daemon.
+func (c *GradleContainer) BuildOpts() types.ContainerConfig {
+	res, err := maven.GetResources(*c.GetBuild())
+	if err != nil {
+		slog.Error("Failed to read container resources", "error", err)
+		os.Exit(1)
+	}
+	gradleOpts := res.MavenOpts
+	if v := c.GetBuild().Custom["gradle_opts"]; len(v) > 0 {
+		gradleOpts = strings.Join(v, " ")
+	}
+	env := []string{
+		fmt.Sprintf("GRADLE_OPTS=%s", gradleOpts),
+		fmt.Sprintf("GRADLE_USER_HOME=%s", CacheLocation),
+	}
+	return maven.ContainerOpts(c.GetBuild(), GradleImage(*c.GetBuild()), env, CacheFolder(), CacheLocation, res)
+}
+
+func (c *GradleContainer) Build() error {
+	opts := c.BuildOpts()
+	opts.Script = c.BuildScript()
+
+	err := c.BuildingContainer(opts)
+	if err != nil {
+		slog.Error("Failed to build container", "error", err)
+		os.Exit(1)
+	}
+
+	return err
+}
+
+func (c *GradleContainer) BuildScript() string {
+	// Create a temporary script in-memory
+	return Script(NewBuildScript(c.Verbose, c.Folder, maven.ContainifyHost(c.GetBuild())))
+}
+
+// Artifact returns the file to ship in the prod image. It is the configured File
+// or, when not set, the war (preferred) or jar Gradle produced in build/libs.
+func Artifact(build container.Build) (string, error) {
+	if build.File != "" {
+		return u.SrcFile(build.File).Host(), nil
+	}
+	libs := filepath.Join(build.Folder, "build", "libs")
+	for _, ext := range []string{"*.war", "*.jar"} {
+		matches, err := filepath.Glob(filepath.Join(libs, ext))
+		if err != nil {
+			return "", err
+		}
+		sort.Strings(matches)
+		for _, match := range matches {
+			base := filepath.Base(match)
+			if strings.HasSuffix(base, "-plain.jar") ||
+				strings.HasSuffix(base, "-sources.jar") ||
+				strings.HasSuffix(base, "-javadoc.jar") {
+				continue
+			}
+			return match, nil
+		}
+	}
+	return "", fmt.Errorf("no artifact found in %s", libs)
+}
+
+// NewProd ships the Gradle artifact with the maven prod image step: jars run
+// with java -jar on a JRE image, wars are deployed to the selected app server.
+func NewProd() build.BuildStep {
+	return build.Stepper{
+		RunFn: func(build container.Build) (string, error) {
+			if build.Image == "" {
+				slog.Info("No image name skip prod image creation")
+				return "", nil
+			}
+			file, err := Artifact(build)
+			if err != nil {
+				slog.Error("Failed to find gradle artifact", "error", err)
+				return "", err
+			}
+			build.File = file
+			c := maven.NewContainer(&build)
+			c.ProbeImage = GradleImage(build)
+			return c.Prod()
+		},
+		ImagesFn: func(build container.Build) []string {
+			return []string{ProdImage(build)}
+		},
+		MatchedFn: IsProject,
+		Name_:     "gradle-prod",
+		Async_:    false,
+	}
+}
+
+func (c *GradleContainer) Run() (string, error) {
+	err := c.Pull()
+	if err != nil {
+		slog.Error("Failed to pull base images: %s", "error", err)
+		return "", err
+	}
+
+	err = c.BuildGradleImage()
+	if err != nil {
+		slog.Error("Failed to build gradle image: %s", "error", err)
+		return "", err
+	}
+
+	err = c.Build()
+	slog.Info("Container created", "containerId", c.ID)
+	if err != nil {
+		slog.Error("Failed to create container: %s", "error", err)
+		return "", err
+	}
+	return c.ID, nil
+}
@@ -0,0 +1,221 @@
+package gradle
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/containifyci/engine-ci/pkg/container"
+	"github.com/containifyci/engine-ci/pkg/cri"
+	"github.com/containifyci/engine-ci/pkg/cri/critest"
+	"github.com/containifyci/engine-ci/pkg/cri/types"
+	"github.com/containifyci/engine-ci/pkg/logger"
+	"github.com/containifyci/engine-ci/pkg/network"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const gradleImage = "containifyci/gradle-8.14.3-eclipse-temurin-v17:1ec4718970d070a74ddee083ebc6099bb2dd1606b84b7785b8236173f984ac8f"
+
+func InitTest(t *testing.T) *container.Build {
+	network.RuntimeOS = "darwin"
+	t.Setenv("SSH_AUTH_SOCK", "/tmp/ssh-auth.sock")
+	t.Setenv("CONTAINER_RUNTIME", "test")
+
+	folder := t.TempDir()
+	err := os.WriteFile(filepath.Join(folder, "build.gradle"), []byte("plugins { id 'war' }\n"), 0o644)
+	require.NoError(t, err)
+
+	logger.NewLogAggregator("")
+	arg := &container.Build{
+		App: "test",
+		Custom: map[string][]string{
+			"CONTAINIFYCI_HOST": {"localhost"},
+			"from":              {"v17"},
+		},
+		Folder:    folder,
+		Image:     "test-image",
+		BuildType: container.Maven,
+	}
+	arg.Defaults()
+	container.NewBuild(arg)
+
+	cRuntime, err := cri.InitContainerRuntime()
+	assert.NoError(t, err)
+	if v, ok := cRuntime.(*critest.MockContainerManager); ok {
+		v.Reset()
+	}
+	return arg
+}
+
+func TestNew(t *testing.T) {
+	build := InitTest(t)
+
+	gc := new(build)
+	matches := Matches(*build)
+	assert.True(t, matches)
+	assert.Equal(t, "gradle", gc.Name())
+	assert.False(t, gc.IsAsync())
+	assert.Equal(t, "test-image", gc.Image)
+	assert.Equal(t, "v17", gc.Version)
+	assert.Equal(t, []string{gradleImage, "tomcat:latest"}, Images(*build))
+}
+
+func TestMatches(t *testing.T) {
+	build := InitTest(t)
+	assert.True(t, Matches(*build))
+
+	// any builder the jdk distribution provides
+	build.Custom["from"] = []string{"v25"}
+	assert.True(t, Matches(*build))
+	build.Custom["from"] = []string{"v99"}
+	assert.False(t, Matches(*build))
+	build.Custom["from"] = []string{"v17"}
+
+	build.Folder = t.TempDir()
+	assert.False(t, Matches(*build))
+
+	build.Custom["build_tool"] = []string{"gradle"}
+	assert.True(t, Matches(*build))
+
+	build.Custom["build_tool"] = []string{"maven"}
+	assert.False(t, Matches(*build))
+}
+
+func TestArtifact(t *testing.T) {
+	build := InitTest(t)
+
+	_, err := Artifact(*build)
+	assert.Error(t, err)
+
+	libs := filepath.Join(build.Folder, "build", "libs")
+	require.NoError(t, os.MkdirAll(libs, 0o755))
+	for _, name := range []string{"app-plain.jar", "app-sources.jar", "app.jar"} {
+		require.NoError(t, os.WriteFile(filepath.Join(libs, name), []byte{}, 0o644))
+	}
+
+	file, err := Artifact(*build)
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(libs, "app.jar"), file)
+
+	require.NoError(t, os.WriteFile(filepath.Join(libs, "app.war"), []byte{}, 0o644))
+	file, err = Artifact(*build)
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(libs, "app.war"), file)
+}
+
+func TestNewProd(t *testing.T) {
+	build := InitTest(t)
+
+	gc := NewProd()
+	assert.Equal(t, "gradle-prod", gc.Name())
+	assert.False(t, gc.IsAsync())
+	assert.True(t, gc.Matches(*build))
+	assert.Equal(t, []string{"tomcat:latest"}, gc.Images(*build))
+}
+
+func TestDockerfile(t *testing.T) {
+	build := InitTest(t)
+
+	dockerFile, err := Dockerfile(*build)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(dockerFile), "FROM eclipse-temurin:17-jdk-jammy\n"))
+	assert.Contains(t, string(dockerFile), " unzip curl ca-certificates && ")
+	assert.Contains(t, string(dockerFile), "ARG GRADLE_VERSION=8.14.3\n")
+
+	build.Custom["jdk"] = []string{"corretto"}
+	build.Custom["from"] = []string{"v21"}
+	dockerFile, err = Dockerfile(*build)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(dockerFile), "FROM amazoncorretto:21-al2023-jdk\n"))
+	// the curl-minimal of the rpm based images already provides curl
+	assert.Contains(t, string(dockerFile), `if ! command -v curl >/dev/null 2>&1; then packages="$packages curl"; fi`)
+	assert.Contains(t, string(dockerFile), "dnf -y install $packages && ")
+	assert.True(t, strings.HasPrefix(GradleImage(*build), "containifyci/gradle-8.14.3-amazon-corretto-v21:"))
+}
+
+func TestBuildOpts(t *testing.T) {
+	arg := InitTest(t)
+	arg.Custom["memory"] = []string{"2g"}
+	arg.Custom["cpu"] = []string{"4096"}
+	t.Setenv("GRADLE_USER_HOME", "/cache/gradle")
+
+	opts := new(arg).BuildOpts()
+	assert.Equal(t, gradleImage, opts.Image)
+	assert.Equal(t, int64(2<<30), opts.Memory)
+	assert.Equal(t, uint64(4096), opts.CPU)
+	assert.Contains(t, opts.Env, "GRADLE_OPTS=-Xms512m -Xmx512m -XX:MaxDirectMemorySize=512m")
+	assert.Contains(t, opts.Volumes, types.Volume{Type: "bind", Source: "/cache/gradle", Target: CacheLocation})
+
+	arg.Custom["gradle_opts"] = []string{"-Xmx1g"}
+	assert.Contains(t, new(arg).BuildOpts().Env, "GRADLE_OPTS=-Xmx1g")
+}
+
+func TestBuildLinuxPodman(t *testing.T) {
+	expectedEnvs := []string{
+		"GRADLE_OPTS=-Xms971m -Xmx971m -XX:MaxDirectMemorySize=971m",
+		"GRADLE_USER_HOME=/root/.gradle/",
+		"SSH_AUTH_SOCK=/tmp/ssh-auth.sock",
+		"CONTAINIFYCI_HOST=localhost",
+		"DOCKER_HOST=unix://var/run/podman.sock",
+		"TESTCONTAINERS_RYUK_DISABLED=true",
+	}
+
+	arg := InitTest(t)
+	arg.Platform.Host.OS = "linux"
+	arg.Runtime = "podman"
+
+	gc := new(arg)
+	err := gc.Build()
+	require.NoError(t, err)
+
+	cRuntime, err := cri.InitContainerRuntime()
+	assert.NoError(t, err)
+
+	if v, ok := cRuntime.(*critest.MockContainerManager); ok {
+		require.Len(t, v.ContainerLogsEntries[gradleImage], 2)
+		assert.Equal(t, []string{"container starting", "container running"}, v.ContainerLogsEntries[gradleImage])
+
+		assert.Equal(t, "started", v.GetContainerByImage(gradleImage).State)
+		assert.Equal(t, []string{"sh", "/tmp/script.sh"}, v.GetContainerByImage(gradleImage).Opts.Cmd)
+		assert.Equal(t, "/src", v.GetContainerByImage(gradleImage).Opts.WorkingDir)
+
+		for _, env := range expectedEnvs {
+			assert.Contains(t, v.GetContainerByImage(gradleImage).Opts.Env, env)
+		}
+	}
+}
+
+func TestProdImage(t *testing.T) {
+	arg := InitTest(t)
+	assert.Equal(t, "war", ArtifactType(*arg))
+	assert.Equal(t, "tomcat:latest", ProdImage(*arg))
+
+	arg.Custom["server"] = []string{"jetty"}
+	assert.Equal(t, "jetty:latest", ProdImage(*arg))
+
+	// jars run on the JRE of the builder JDK
+	require.NoError(t, os.WriteFile(filepath.Join(arg.Folder, "build.gradle"), []byte("plugins { id 'org.springframework.boot' version '3.3.0' }\n"), 0o644))
+	assert.Equal(t, "jar", ArtifactType(*arg))
+	assert.Equal(t, "eclipse-temurin:17-jre-jammy", ProdImage(*arg))
+
+	// the built artifact wins over the build files
+	libs := filepath.Join(arg.Folder, "build", "libs")
+	require.NoError(t, os.MkdirAll(libs, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(libs, "app.war"), []byte{}, 0o644))
+	assert.Equal(t, "war", ArtifactType(*arg))
+}
+
+func TestProd(t *testing.T) {
+	arg := InitTest(t)
+	gc := NewProd()
+
+	_, err := gc.RunWithBuild(*arg)
+	assert.ErrorContains(t, err, "no artifact found")
+
+	arg.Image = ""
+	id, err := gc.RunWithBuild(*arg)
+	assert.NoError(t, err)
+	assert.Empty(t, id)
+}
@@ -0,0 +1,85 @@
+package maven
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/containifyci/engine-ci/pkg/container"
+)
+
+const DEFAULT_SERVER = "tomcat"
+
+// AppServer describes how web applications are deployed to an application server image.
+type AppServer struct {
+	// Name is the value of the server property selecting the app server.
+	Name  string
+	Image string
+	// DeployDir is the folder the server picks up deployments from.
+	DeployDir string
+	// ConfigDir receives the files listed in the server_config property.
+	ConfigDir string
+	// Cmd starts the server in the foreground.
+	Cmd []string
+	// Port is the HTTP port the server listens on.
+	Port string
+	// Artifacts are the artifact types the server can deploy.
+	Artifacts []string
+}
+
+var AppServers = []AppServer{
+	{
+		Name:      "tomcat",
+		Image:     PRODIMAGE,
+		DeployDir: "/usr/local/tomcat/webapps/",
+		ConfigDir: "/usr/local/tomcat/conf/",
+		Cmd:       []string{"catalina.sh", "run"},
+		Port:      "8080",
+		Artifacts: []string{ArtifactWar},
+	},
+	{
+		Name:      "jetty",
+		Image:     "jetty:latest",
+		DeployDir: "/var/lib/jetty/webapps/",
+		ConfigDir: "/var/lib/jetty/start.d/",
+		Cmd:       []string{"java", "-jar", "/usr/local/jetty/start.jar"},
+		Port:      "8080",
+		Artifacts: []string{ArtifactWar},
+	},
+	{
+		Name:      "wildfly",
+		Image:     "quay.io/wildfly/wildfly:latest",
+		DeployDir: "/opt/jboss/wildfly/standalone/deployments/",
+		ConfigDir: "/opt/jboss/wildfly/standalone/configuration/",
+		Cmd:       []string{"/opt/jboss/wildfly/bin/standalone.sh", "-b", "0.0.0.0"},
+		Port:      "8080",
+		Artifacts: []string{ArtifactWar, ArtifactEar},
+	},
+	{
+		Name:      "openliberty",
+		Image:     "icr.io/appcafe/open-liberty:latest",
+		DeployDir: "/config/dropins/",
+		ConfigDir: "/config/",
+		Cmd:       []string{"/opt/ol/wlp/bin/server", "run", "defaultServer"},
+		Port:      "9080",
+		Artifacts: []string{ArtifactWar, ArtifactEar},
+	},
+}
+
+// GetAppServer returns the app server selected by the server property, tomcat by default.
+func GetAppServer(build container.Build) (AppServer, error) {
+	name := build.Custom.String("server")
+	if name == "" {
+		name = DEFAULT_SERVER
+	}
+	for _, server := range AppServers {
+		if server.Name == name {
+			return server, nil
+		}
+	}
+	return AppServer{}, fmt.Errorf("unsupported app server %q", name)
+}
+
+// Deploys reports whether the server can deploy the artifact type.
+func (s AppServer) Deploys(artifact string) bool {
+	return slices.Contains(s.Artifacts, artifact)
+}
@@ -0,0 +1,54 @@
+package maven
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetAppServer(t *testing.T) {
+	build := InitTest(t)
+
+	server, err := GetAppServer(*build)
+	require.NoError(t, err)
+	assert.Equal(t, "tomcat", server.Name)
+	assert.Equal(t, "/usr/local/tomcat/webapps/", server.DeployDir)
+	assert.Equal(t, []string{"catalina.sh", "run"}, server.Cmd)
+	assert.True(t, server.Deploys(ArtifactWar))
+	assert.False(t, server.Deploys(ArtifactEar))
+
+	build.Custom["server"] = []string{"unknown"}
+	_, err = GetAppServer(*build)
+	assert.Error(t, err)
+}
+
+func TestAppServers(t *testing.T) {
+	tests := []struct {
+		server    string
+		image     string
+		deployDir string
+		cmd       []string
+		ear       bool
+	}{
+		{"jetty", "jetty:latest", "/var/lib/jetty/webapps/", []string{"java", "-jar", "/usr/local/jetty/start.jar"}, false},
+		{"wildfly", "quay.io/wildfly/wildfly:latest", "/opt/jboss/wildfly/standalone/deployments/", []string{"/opt/jboss/wildfly/bin/standalone.sh", "-b", "0.0.0.0"}, true},
+		{"openliberty", "icr.io/appcafe/open-liberty:latest", "/config/dropins/", []string{"/opt/ol/wlp/bin/server", "run", "defaultServer"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.server, func(t *testing.T) {
+			build := InitTest(t)
+			build.File = "target/app.war"
+			build.Custom["server"] = []string{tt.server}
+
+			server, err := GetAppServer(*build)
+			require.NoError(t, err)
+			image, err := ProdImage(*build)
+			require.NoError(t, err)
+			assert.Equal(t, tt.image, image)
+			assert.Equal(t, tt.deployDir, server.DeployDir)
+			assert.Equal(t, tt.cmd, server.Cmd)
+			assert.Equal(t, tt.ear, server.Deploys(ArtifactEar))
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package maven
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/containifyci/engine-ci/pkg/container"
+)
+
+const (
+	ArtifactWar = "war"
+	ArtifactEar = "ear"
+	ArtifactJar = "jar"
+
+	DEFAULT_PORT = "8080"
+	// JarLocation is where the executable jar is placed in the prod image.
+	JarLocation = "/opt/app.jar"
+)
+
+// ArtifactType returns jar for executable jar services and war or ear for
+// applications deployed to an app server. It is read from the extension of
+// File, then from the pom packaging. Other packagings, like the pom of an
+// aggregator, and projects without a pom.xml ship no artifact and fail.
+func ArtifactType(build container.Build) (string, error) {
+	switch strings.ToLower(filepath.Ext(build.File)) {
+	case ".jar":
+		return ArtifactJar, nil
+	case ".war":
+		return ArtifactWar, nil
+	case ".ear":
+		return ArtifactEar, nil
+	}
+	pom, err := ReadPom(filepath.Join(build.Folder, PomFile))
+	if err != nil {
+		return "", fmt.Errorf("no jar, war or ear file configured and no pom.xml to derive the artifact type from: %w", err)
+	}
+	switch pom.Packaging {
+	case "", ArtifactJar:
+		return ArtifactJar, nil
+	case ArtifactWar:
+		return ArtifactWar, nil
+	case ArtifactEar:
+		return ArtifactEar, nil
+	}
+	return "", fmt.Errorf("unsupported packaging %q, configure the jar, war or ear file to ship", pom.Packaging)
+}
+
+// Artifact returns the file to ship in the prod image, File when set or the
+// target/<finalName>.<packaging> Maven produces otherwise.
+func Artifact(build container.Build) (string, error) {
+	if build.File != "" {
+		return build.File, nil
+	}
+	pom, err := ReadPom(filepath.Join(build.Folder, PomFile))
+	if err != nil {
+		return "", fmt.Errorf("no file configured and no pom.xml to derive it from: %w", err)
+	}
+	name := pom.Resolve(pom.FinalName)
+	if name == "" {
+		version := pom.Version
+		if version == "" && pom.Parent != nil {
+			version = pom.Parent.Version
+		}
+		name = fmt.Sprintf("%s-%s", pom.ArtifactID, pom.Resolve(version))
+	}
+	packaging := pom.Packaging
+	if packaging == "" {
+		packaging = ArtifactJar
+	}
+	file := filepath.Join(build.Folder, "target", name+"."+packaging)
+	if _, err := os.Stat(file); err != nil {
+		return "", err
+	}
+	return file, nil
+}
+
+// ApplyJarConfig configures spec to run the jar with the JVM options from
+// jvm_opts and to expose the port from port.
+func ApplyJarConfig(build container.Build, spec *ProdSpec) {
+	port := build.Custom.String("port")
+	if port == "" {
+		port = DEFAULT_PORT
+	}
+	spec.Ports = []string{port}
+	spec.Entrypoint = []string{"java", "-jar", JarLocation}
+	if opts := build.Custom["jvm_opts"]; len(opts) > 0 {
+		spec.Env = append(spec.Env, "JAVA_TOOL_OPTIONS="+strings.Join(opts, " "))
+	}
+}
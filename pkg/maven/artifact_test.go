@@ -0,0 +1,126 @@
+package maven
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArtifactType(t *testing.T) {
+	build := InitTest(t)
+	build.File = "target/service.jar"
+	artifact, err := ArtifactType(*build)
+	require.NoError(t, err)
+	assert.Equal(t, ArtifactJar, artifact)
+
+	build.File = ""
+	build.Folder = t.TempDir()
+	_, err = ArtifactType(*build)
+	assert.Error(t, err)
+
+	tests := []struct {
+		packaging string
+		artifact  string
+	}{
+		{"", ArtifactJar},
+		{"<packaging>jar</packaging>", ArtifactJar},
+		{"<packaging>war</packaging>", ArtifactWar},
+		{"<packaging>ear</packaging>", ArtifactEar},
+	}
+	for _, tt := range tests {
+		writePom(t, build.Folder, `<project><artifactId>service</artifactId>`+tt.packaging+`</project>`)
+		artifact, err := ArtifactType(*build)
+		require.NoError(t, err)
+		assert.Equal(t, tt.artifact, artifact)
+	}
+
+	writePom(t, build.Folder, `<project><artifactId>service</artifactId><packaging>pom</packaging></project>`)
+	_, err = ArtifactType(*build)
+	assert.ErrorContains(t, err, `unsupported packaging "pom"`)
+	_, err = ProdImage(*build)
+	assert.Error(t, err)
+	assert.Equal(t, []string{MavenImage(*build)}, Images(*build))
+}
+
+func TestArtifact(t *testing.T) {
+	build := InitTest(t)
+	build.Folder = t.TempDir()
+	writePom(t, build.Folder, `<project>
+		<artifactId>service</artifactId>
+		<version>${revision}</version>
+		<properties><revision>1.2.0</revision></properties>
+	</project>`)
+
+	_, err := Artifact(*build)
+	assert.Error(t, err)
+
+	jar := filepath.Join(build.Folder, "target", "service-1.2.0.jar")
+	require.NoError(t, os.MkdirAll(filepath.Dir(jar), 0o755))
+	require.NoError(t, os.WriteFile(jar, []byte{}, 0o644))
+
+	file, err := Artifact(*build)
+	assert.NoError(t, err)
+	assert.Equal(t, jar, file)
+
+	build.File = "target/other.jar"
+	file, err = Artifact(*build)
+	assert.NoError(t, err)
+	assert.Equal(t, "target/other.jar", file)
+}
+
+func TestFinalNameArtifact(t *testing.T) {
+	build := InitTest(t)
+	build.Folder = t.TempDir()
+	writePom(t, build.Folder, `<project>
+		<artifactId>hello-world-servlet</artifactId>
+		<version>1.0.0</version>
+		<packaging>war</packaging>
+		<build><finalName>hello-world-servlet</finalName></build>
+	</project>`)
+
+	war := filepath.Join(build.Folder, "target", "hello-world-servlet.war")
+	require.NoError(t, os.MkdirAll(filepath.Dir(war), 0o755))
+	require.NoError(t, os.WriteFile(war, []byte{}, 0o644))
+
+	file, err := Artifact(*build)
+	assert.NoError(t, err)
+	assert.Equal(t, war, file)
+}
+
+func TestApplyJarConfig(t *testing.T) {
+	build := InitTest(t)
+	spec := NewProdSpec("eclipse-temurin:17-jre-jammy")
+	ApplyJarConfig(*build, spec)
+	assert.Equal(t, []string{"8080"}, spec.Ports)
+	assert.Equal(t, []string{"java", "-jar", "/opt/app.jar"}, spec.Entrypoint)
+	assert.Empty(t, spec.Env)
+
+	build.Custom["port"] = []string{"9090"}
+	build.Custom["jvm_opts"] = []string{"-Xmx1g", "-XX:+UseZGC"}
+	spec = NewProdSpec("eclipse-temurin:17-jre-jammy")
+	ApplyJarConfig(*build, spec)
+	assert.Equal(t, []string{"9090"}, spec.Ports)
+	assert.Equal(t, []string{"JAVA_TOOL_OPTIONS=-Xmx1g -XX:+UseZGC"}, spec.Env)
+}
+
+func TestJarProdImage(t *testing.T) {
+	build := InitTest(t)
+	build.File = "target/service.jar"
+	image, err := ProdImage(*build)
+	require.NoError(t, err)
+	assert.Equal(t, "eclipse-temurin:17-jre-jammy", image)
+
+	build.Custom["jdk"] = []string{"corretto"}
+	build.Custom["from"] = []string{"v21"}
+	image, err = ProdImage(*build)
+	require.NoError(t, err)
+	assert.Equal(t, "amazoncorretto:21-al2023-headless", image)
+
+	build.Custom["image"] = []string{"gcr.io/distroless/java21"}
+	image, err = ProdImage(*build)
+	require.NoError(t, err)
+	assert.Equal(t, "gcr.io/distroless/java21", image)
+}
@@ -0,0 +1,243 @@
+package maven
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/containifyci/engine-ci/pkg/container"
+)
+
+const (
+	BuildCacheGroupID           = "org.apache.maven.extensions"
+	BuildCacheArtifactID        = "maven-build-cache-extension"
+	DEFAULT_BUILD_CACHE_VERSION = "1.2.0"
+
+	ExtensionsFile       = ".mvn/extensions.xml"
+	BuildCacheConfigFile = ".mvn/maven-build-cache-config.xml"
+	// BuildCacheConfigPath is the default cache configuration inside the
+	// build container, used unless the project has its own.
+	BuildCacheConfigPath = "/tmp/containifyci/maven-build-cache-config.xml"
+	// BuildCacheLocation keeps the cached builds in the cache mount next to
+	// the local repository.
+	BuildCacheLocation = CacheLocation + "build-cache"
+)
+
+var buildCacheVersion = regexp.MustCompile(`^\d+\.\d+(\.\d+)?$`)
+
+// BuildCache enables the incremental builds of the maven-build-cache-extension.
+type BuildCache struct {
+	Version string
+	// ProjectConfig is set when the project has its own BuildCacheConfigFile.
+	ProjectConfig bool
+}
+
+// GetBuildCache reads the build_cache and build_cache_version properties. It
+// returns nil unless build_cache is enabled.
+func GetBuildCache(build container.Build) (*BuildCache, error) {
+	if !build.Custom.Bool("build_cache", false) {
+		return nil, nil
+	}
+	version := build.Custom.String("build_cache_version")
+	if version == "" {
+		version = DEFAULT_BUILD_CACHE_VERSION
+	}
+	if !buildCacheVersion.MatchString(version) {
+		return nil, fmt.Errorf("invalid build_cache_version %q", version)
+	}
+	_, err := os.Stat(filepath.Join(build.Folder, BuildCacheConfigFile))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	return &BuildCache{Version: version, ProjectConfig: err == nil}, nil
+}
+
+// Properties point the extension to the cache mount and, without a project
+// configuration, to the default one. Read-only builds restore from the cache
+// without saving to it.
+func (b *BuildCache) Properties(readOnly bool) []string {
+	props := []string{"maven.build.cache.location=" + BuildCacheLocation}
+	if !b.ProjectConfig {
+		props = append(props, "maven.build.cache.configPath="+BuildCacheConfigPath)
+	}
+	if readOnly {
+		props = append(props, "maven.build.cache.skipSave=true")
+	}
+	return props
+}
+
+type extensionsXML struct {
+	XMLName    xml.Name       `xml:"extensions"`
+	Xmlns      string         `xml:"xmlns,attr,omitempty"`
+	Extensions []extensionXML `xml:"extension"`
+}
+
+type extensionXML struct {
+	GroupID    string `xml:"groupId"`
+	ArtifactID string `xml:"artifactId"`
+	Version    string `xml:"version"`
+}
+
+const buildCacheConfig = `<?xml version="1.0" encoding="UTF-8"?>
+<cache xmlns="http://maven.apache.org/BUILD-CACHE-CONFIG/1.0.0">
+  <configuration>
+    <enabled>true</enabled>
+    <hashAlgorithm>XX</hashAlgorithm>
+  </configuration>
+</cache>
+`
+
+// Setup renders the shell lines writing the default cache configuration in
+// the container, nothing when the project has its own.
+func (b *BuildCache) Setup() string {
+	if b.ProjectConfig {
+		return ""
+	}
+	return fmt.Sprintf("mkdir -p %s\nprintf '%%s' %s > %s\n",
+		shellQuote(filepath.Dir(BuildCacheConfigPath)), shellQuote(buildCacheConfig), shellQuote(BuildCacheConfigPath))
+}
+
+// Provision writes the extensions.xml declaring the extension to the project
+// in folder unless it has one, and returns the files it wrote for Cleanup. An
+// extensions.xml without the extension is left to the project.
+func (b *BuildCache) Provision(folder string) ([]string, error) {
+	var written []string
+	file := filepath.Join(folder, ExtensionsFile)
+	data, err := os.ReadFile(file)
+	switch {
+	case os.IsNotExist(err):
+		doc := extensionsXML{
+			Xmlns:      "http://maven.apache.org/EXTENSIONS/1.1.0",
+			Extensions: []extensionXML{{GroupID: BuildCacheGroupID, ArtifactID: BuildCacheArtifactID, Version: b.Version}},
+		}
+		out, err := xml.MarshalIndent(doc, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		if err := writeProjectFile(file, append([]byte(xml.Header), append(out, '\n')...)); err != nil {
+			return nil, err
+		}
+		written = append(written, ExtensionsFile)
+	case err != nil:
+		return nil, err
+	default:
+		var doc extensionsXML
+		if err := xml.Unmarshal(data, &doc); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", ExtensionsFile, err)
+		}
+		found := false
+		for _, ext := range doc.Extensions {
+			found = found || (ext.GroupID == BuildCacheGroupID && ext.ArtifactID == BuildCacheArtifactID)
+		}
+		if !found {
+			return nil, fmt.Errorf("%s does not declare %s:%s, add it to enable the build cache", ExtensionsFile, BuildCacheGroupID, BuildCacheArtifactID)
+		}
+	}
+
+	return written, nil
+}
+
+func writeProjectFile(file string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(file, data, 0o644)
+}
+
+// Cleanup removes the files Provision wrote to the project in folder, and
+// their folders once empty, so the build leaves the source tree as it was.
+func (b *BuildCache) Cleanup(folder string, written []string) error {
+	for _, file := range written {
+		if err := os.Remove(filepath.Join(folder, file)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		// a folder still holding files of the project stays
+		_ = os.Remove(filepath.Join(folder, filepath.Dir(file)))
+	}
+	return nil
+}
+
+// CacheReport lists the modules restored from the build cache and the ones
+// that were built, by artifactId in reactor order.
+type CacheReport struct {
+	Restored []string
+	Built    []string
+}
+
+var (
+	// projectHeader matches the banner Maven prints when it starts a module,
+	// e.g. ---< com.example:core >---
+	projectHeader = regexp.MustCompile(`^\[INFO\] -+< ([^:\s]+):([^:\s]+) >-+$`)
+	cacheRestored = regexp.MustCompile(`Found cached build, restoring (?:[^:\s]+:)?([^:\s]+) from cache`)
+)
+
+// ParseBuildCacheLog reads which modules the extension restored from the mvn
+// output, the other modules Maven started were built.
+func ParseBuildCacheLog(r io.Reader) (*CacheReport, error) {
+	var modules []string
+	seen := map[string]bool{}
+	restored := map[string]bool{}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if m := projectHeader.FindStringSubmatch(line); m != nil {
+			if !seen[m[2]] {
+				seen[m[2]] = true
+				modules = append(modules, m[2])
+			}
+			continue
+		}
+		if m := cacheRestored.FindStringSubmatch(line); m != nil {
+			restored[m[1]] = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	report := &CacheReport{}
+	for _, module := range modules {
+		if restored[module] {
+			report.Restored = append(report.Restored, module)
+		} else {
+			report.Built = append(report.Built, module)
+		}
+	}
+	return report, nil
+}
+
+// readBuildLog parses the BuildLog of folder, nil when the build kept none.
+func readBuildLog(folder string) (*CacheReport, error) {
+	fh, err := os.Open(filepath.Join(folder, BuildLog))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer fh.Close()
+	return ParseBuildCacheLog(fh)
+}
+
+// ReportBuildCache logs the modules restored from the build cache in the
+// BuildLog of folder.
+func ReportBuildCache(folder string) error {
+	report, err := readBuildLog(folder)
+	if err != nil || report == nil {
+		return err
+	}
+	slog.Info("Build cache", "restored", len(report.Restored), "built", len(report.Built))
+	if len(report.Restored) > 0 {
+		slog.Info("Modules restored from the build cache", "modules", strings.Join(report.Restored, ","))
+	}
+	if len(report.Built) > 0 {
+		slog.Info("Modules built", "modules", strings.Join(report.Built, ","))
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package maven
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetBuildCache(t *testing.T) {
+	build := InitTest(t)
+
+	cache, err := GetBuildCache(*build)
+	require.NoError(t, err)
+	assert.Nil(t, cache)
+
+	build.Custom["build_cache"] = []string{"true"}
+	cache, err = GetBuildCache(*build)
+	require.NoError(t, err)
+	assert.Equal(t, &BuildCache{Version: DEFAULT_BUILD_CACHE_VERSION}, cache)
+	assert.Equal(t, []string{"maven.build.cache.location=/root/.m2/build-cache", "maven.build.cache.configPath=/tmp/containifyci/maven-build-cache-config.xml"}, cache.Properties(false))
+	assert.Contains(t, cache.Properties(true), "maven.build.cache.skipSave=true")
+
+	// a configuration of the project is used as is
+	build.Folder = t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(build.Folder, ".mvn"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(build.Folder, BuildCacheConfigFile), []byte("<cache/>"), 0o644))
+	cache, err = GetBuildCache(*build)
+	require.NoError(t, err)
+	assert.True(t, cache.ProjectConfig)
+	assert.Equal(t, []string{"maven.build.cache.location=/root/.m2/build-cache"}, cache.Properties(false))
+	assert.Empty(t, cache.Setup())
+
+	build.Custom["build_cache_version"] = []string{"1.2"}
+	cache, err = GetBuildCache(*build)
+	require.NoError(t, err)
+	assert.Equal(t, "1.2", cache.Version)
+
+	build.Custom["build_cache_version"] = []string{"latest"}
+	_, err = GetBuildCache(*build)
+	assert.Error(t, err)
+}
+
+func TestBuildCacheProvision(t *testing.T) {
+	folder := t.TempDir()
+	cache := &BuildCache{Version: "1.2.0"}
+
+	written, err := cache.Provision(folder)
+	require.NoError(t, err)
+	assert.Equal(t, []string{ExtensionsFile}, written)
+	data, err := os.ReadFile(filepath.Join(folder, ExtensionsFile))
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "<artifactId>maven-build-cache-extension</artifactId>\n    <version>1.2.0</version>")
+	// the configuration is written in the container, not to the project
+	assert.NoFileExists(t, filepath.Join(folder, BuildCacheConfigFile))
+	assert.Contains(t, cache.Setup(), "> /tmp/containifyci/maven-build-cache-config.xml\n")
+
+	// the build leaves the source tree as it was
+	require.NoError(t, cache.Cleanup(folder, written))
+	assert.NoDirExists(t, filepath.Join(folder, ".mvn"))
+
+	// the files of the project are kept
+	require.NoError(t, os.MkdirAll(filepath.Join(folder, ".mvn"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(folder, ExtensionsFile), []byte(`<extensions>
+  <extension>
+    <groupId>org.apache.maven.extensions</groupId>
+    <artifactId>maven-build-cache-extension</artifactId>
+    <version>1.2.0</version>
+  </extension>
+</extensions>`), 0o644))
+	written, err = cache.Provision(folder)
+	require.NoError(t, err)
+	assert.Empty(t, written)
+	require.NoError(t, cache.Cleanup(folder, written))
+	assert.FileExists(t, filepath.Join(folder, ExtensionsFile))
+
+	require.NoError(t, os.WriteFile(filepath.Join(folder, ExtensionsFile), []byte(`<extensions>
+  <extension>
+    <groupId>kr.motd.maven</groupId>
+    <artifactId>os-maven-plugin</artifactId>
+    <version>1.7.1</version>
+  </extension>
+</extensions>`), 0o644))
+	_, err = cache.Provision(folder)
+	assert.ErrorContains(t, err, "does not declare org.apache.maven.extensions:maven-build-cache-extension")
+}
+
+const buildCacheLog = `[INFO] Reactor Build Order:
+[INFO] ----------------------< com.example:parent >-----------------------
+[INFO] Building parent 1.0-SNAPSHOT                                  [1/3]
+[INFO] Found cached build, restoring com.example:parent from cache by checksum 8a3f
+[INFO] ------------------------< com.example:core >------------------------
+[INFO] ------------------------< com.example:web >-------------------------
+[INFO] Local build was not found by checksum 1b2c for com.example:core
+[INFO] Found cached build, restoring com.example:web from cache by checksum 77ee
+[INFO] Skipping plugin execution (cached): compiler:compile
+`
+
+func TestParseBuildCacheLog(t *testing.T) {
+	report, err := ParseBuildCacheLog(strings.NewReader(buildCacheLog))
+	require.NoError(t, err)
+	assert.Equal(t, &CacheReport{Restored: []string{"parent", "web"}, Built: []string{"core"}}, report)
+}
@@ -1,13 +1,69 @@
 package maven
 
-import "fmt"
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
 
 type Image string
 
+const DEFAULT_GOAL = "package"
+
+// DefaultPrefetchGoals resolve the dependencies and plugins of the project
+// before an offline build.
+var DefaultPrefetchGoals = []string{"dependency:go-offline", "dependency:resolve-plugins"}
+
+const (
+	buildLog    = "/tmp/containifyci/build.log"
+	buildStatus = "/tmp/containifyci/build.status"
+)
+
+// BuildLog is the copy of the build output in the project folder the reactor
+// summary is read from. It is copied once mvn is done, as clean would delete
+// it from target/ during the build.
+const BuildLog = "target/maven-build.log"
+
 type BuildScript struct {
 	Verbose bool
 	Folder  string
 	Host    string
+
+	// Goals are the lifecycle phases and plugin goals to run, package by default.
+	Goals []string
+	// Profiles are activated with -P.
+	Profiles []string
+	// Properties are passed as -Dkey=value system properties.
+	Properties []string
+	// Args are appended verbatim (after escaping) to the mvn command line.
+	Args      []string
+	SkipTests bool
+	// Wrapper runs the project's mvnw instead of the mvn of the builder image.
+	Wrapper *Wrapper
+	// Settings are written to SettingsFile and passed as global settings, so
+	// Maven merges them with the user settings.xml of the cache mount.
+	Settings *Settings
+	// BuildCache writes the default build cache configuration when set.
+	BuildCache *BuildCache
+	// CacheScope selects the local repository, its Properties are part of
+	// Properties.
+	CacheScope *CacheScope
+	// Reactor selects the modules to build, the whole reactor when nil.
+	Reactor *Reactor
+	// Offline builds with --offline, PrefetchScript resolves the
+	// PrefetchGoals for it beforehand.
+	Offline       bool
+	PrefetchGoals []string
+	// Threads builds the reactor modules in parallel, serially when empty or 1.
+	Threads string
+	// KeepLog copies the build output to BuildLog.
+	KeepLog bool
+	// Daemon runs mvnd instead of mvn or the wrapper.
+	Daemon bool
+	// DaemonJVMArgs are the options of the mvnd daemon JVM, MAVEN_OPTS only
+	// reaches the mvnd client.
+	DaemonJVMArgs string
 }
 
 func NewBuildScript(verbose bool, folder, host string) *BuildScript {
@@ -15,28 +71,119 @@ func NewBuildScript(verbose bool, folder, host string) *BuildScript {
 		Verbose: verbose,
 		Folder:  folder,
 		Host:    host,
+		Goals:   []string{DEFAULT_GOAL},
 	}
 }
 
 func Script(bs *BuildScript) string {
-	if bs.Verbose {
-		return verboseScript(bs)
+	var sb strings.Builder
+	sb.WriteString("#!/bin/sh\nset -xe\n")
+	fmt.Fprintf(&sb, "cd %s\n", shellQuote(bs.Folder))
+	if bs.Settings != nil {
+		sb.WriteString(bs.Settings.Setup())
+	}
+	if bs.BuildCache != nil {
+		sb.WriteString(bs.BuildCache.Setup())
+	}
+	if bs.Wrapper != nil && !bs.Daemon {
+		sb.WriteString(bs.Wrapper.Setup())
+	}
+	if !bs.Offline && !bs.KeepLog {
+		sb.WriteString(bs.Command() + "\n")
+		return sb.String()
+	}
+
+	// the output of the build is kept to point out network access as the
+	// cause of a failed offline build and for the reactor summary
+	fmt.Fprintf(&sb, "mkdir -p %s\nrm -f %s\n", path.Dir(buildStatus), buildStatus)
+	fmt.Fprintf(&sb, "{ %s || echo $? > %s; } 2>&1 | tee %s\n", bs.Command(), buildStatus, buildLog)
+	if bs.KeepLog {
+		fmt.Fprintf(&sb, "mkdir -p %s\ncp %s %s\n", path.Dir(BuildLog), buildLog, BuildLog)
+	}
+	fmt.Fprintf(&sb, "if [ -f %s ]; then\n", buildStatus)
+	if bs.Offline {
+		fmt.Fprintf(&sb, "  if grep -q -e 'offline mode' -e 'UnknownHostException' -e 'Network is unreachable' %s; then echo 'ERROR: the offline build tried to reach the network, add the missing dependencies or plugins to the prefetch_goals' >&2; fi\n", buildLog)
+	}
+	fmt.Fprintf(&sb, "  exit \"$(cat %s)\"\nfi\n", buildStatus)
+	return sb.String()
+}
+
+// PrefetchScript renders the run resolving the PrefetchGoals with network
+// access ahead of an offline build.
+func PrefetchScript(bs *BuildScript) string {
+	prefetch := *bs
+	prefetch.Goals = bs.PrefetchGoals
+	if len(prefetch.Goals) == 0 {
+		prefetch.Goals = DefaultPrefetchGoals
+	}
+	prefetch.Offline = false
+	prefetch.KeepLog = false
+	return Script(&prefetch)
+}
+
+// Command renders the mvn invocation with every argument shell-escaped.
+func (bs *BuildScript) Command() string {
+	goals := bs.Goals
+	if len(goals) == 0 {
+		goals = []string{DEFAULT_GOAL}
 	}
-	return simpleScript(bs)
+	return bs.command(goals, bs.Offline)
 }
 
-func simpleScript(bs *BuildScript) string {
-	return fmt.Sprintf(`#!/bin/sh
-set -xe
-cd %s
-mvn --batch-mode package
-`, bs.Folder)
+func (bs *BuildScript) command(goals []string, offline bool) string {
+	args := []string{"mvn", "--batch-mode"}
+	switch {
+	case bs.Daemon:
+		// the daemons of different builder containers must not see each
+		// other in the registry of the shared cache mount
+		args = []string{"mvnd", "--batch-mode", "-Dmvnd.daemonStorage=" + daemonStorage}
+		if bs.DaemonJVMArgs != "" {
+			args = append(args, "-Dmvnd.jvmArgs="+bs.DaemonJVMArgs)
+		}
+	case bs.Wrapper != nil:
+		args = []string{"sh", "./" + WrapperScript, "--batch-mode"}
+	}
+	if offline {
+		args = append(args, "--offline")
+	}
+	if bs.Settings != nil {
+		args = append(args, "--global-settings", SettingsFile)
+	}
+	if bs.Threads != "" && bs.Threads != "1" {
+		args = append(args, "--threads", bs.Threads)
+	}
+	args = append(args, goals...)
+	if bs.Reactor != nil {
+		args = append(args, bs.Reactor.Args()...)
+	}
+	if len(bs.Profiles) > 0 {
+		args = append(args, "-P"+strings.Join(bs.Profiles, ","))
+	}
+	for _, prop := range bs.Properties {
+		args = append(args, "-D"+prop)
+	}
+	if bs.SkipTests {
+		args = append(args, "-DskipTests")
+	}
+	args = append(args, bs.Args...)
+	if bs.Verbose {
+		args = append(args, "-X")
+	}
+
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = shellQuote(arg)
+	}
+	return strings.Join(quoted, " ")
 }
 
-func verboseScript(bs *BuildScript) string {
-	return fmt.Sprintf(`#!/bin/sh
-set -xe
-cd %s
-mvn --batch-mode package -X
-`, bs.Folder)
+var safeShellArg = regexp.MustCompile(`^[A-Za-z0-9_@%+=:,./-]+$`)
+
+// shellQuote wraps s in single quotes unless it only contains characters
+// that the shell treats literally.
+func shellQuote(s string) string {
+	if safeShellArg.MatchString(s) {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
 }
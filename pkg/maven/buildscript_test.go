@@ -1,9 +1,13 @@
 package maven
 
 import (
+	"os"
+	"os/exec"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestSimpleScript(t *testing.T) {
@@ -19,3 +23,101 @@ func TestVerboseScript(t *testing.T) {
 
 	assert.Equal(t, "#!/bin/sh\nset -xe\ncd java\nmvn --batch-mode package -X\n", script)
 }
+
+func TestConfiguredScript(t *testing.T) {
+	bs := NewBuildScript(false, "my app", "localhost")
+	bs.Goals = []string{"clean", "verify"}
+	bs.Profiles = []string{"ci", "release"}
+	bs.Properties = []string{"revision=1.0.0", "greeting=hello world"}
+	bs.Args = []string{"-U", "--settings=it's.xml"}
+	bs.SkipTests = true
+	script := Script(bs)
+
+	assert.Equal(t, "#!/bin/sh\nset -xe\ncd 'my app'\nmvn --batch-mode clean verify -Pci,release -Drevision=1.0.0 '-Dgreeting=hello world' -DskipTests -U '--settings=it'\\''s.xml'\n", script)
+}
+
+func TestEmptyGoalsFallBackToPackage(t *testing.T) {
+	bs := NewBuildScript(true, ".", "localhost")
+	bs.Goals = nil
+
+	assert.Equal(t, "mvn --batch-mode package -X", bs.Command())
+}
+
+func TestThreadsScript(t *testing.T) {
+	bs := NewBuildScript(false, ".", "localhost")
+	bs.Threads = "1"
+	assert.Equal(t, "mvn --batch-mode package", bs.Command())
+
+	bs.Threads = "1.5C"
+	assert.Equal(t, "mvn --batch-mode --threads 1.5C package", bs.Command())
+}
+
+func TestDaemonScript(t *testing.T) {
+	bs := NewBuildScript(false, ".", "localhost")
+	bs.Daemon = true
+	bs.Wrapper = &Wrapper{DistributionURL: "https://repo.maven.apache.org/maven2/org/apache/maven/apache-maven/3.9.9/apache-maven-3.9.9-bin.zip"}
+
+	assert.Equal(t, "#!/bin/sh\nset -xe\ncd .\nmvnd --batch-mode -Dmvnd.daemonStorage=/tmp/containifyci/mvnd package\n", Script(bs))
+
+	bs.DaemonJVMArgs = "-Xms971m -Xmx971m"
+	assert.Contains(t, Script(bs), "\nmvnd --batch-mode -Dmvnd.daemonStorage=/tmp/containifyci/mvnd '-Dmvnd.jvmArgs=-Xms971m -Xmx971m' package\n")
+}
+
+func TestKeepLogScript(t *testing.T) {
+	bs := NewBuildScript(false, ".", "localhost")
+	bs.KeepLog = true
+	script := Script(bs)
+
+	assert.Equal(t, "#!/bin/sh\nset -xe\ncd .\nmkdir -p /tmp/containifyci\nrm -f /tmp/containifyci/build.status\n"+
+		"{ mvn --batch-mode package || echo $? > /tmp/containifyci/build.status; } 2>&1 | tee /tmp/containifyci/build.log\n"+
+		"mkdir -p target\ncp /tmp/containifyci/build.log target/maven-build.log\n"+
+		"if [ -f /tmp/containifyci/build.status ]; then\n  exit \"$(cat /tmp/containifyci/build.status)\"\nfi\n", script)
+}
+
+func TestOfflineScript(t *testing.T) {
+	bs := NewBuildScript(false, ".", "localhost")
+	bs.Goals = []string{"verify"}
+	bs.Offline = true
+	script := Script(bs)
+
+	assert.NotContains(t, script, "dependency:go-offline")
+	assert.NotContains(t, script, "proxy")
+	assert.Contains(t, script, "{ mvn --batch-mode --offline verify || echo $? > /tmp/containifyci/build.status; } 2>&1 | tee /tmp/containifyci/build.log\n")
+
+	assert.Equal(t, "#!/bin/sh\nset -xe\ncd .\nmvn --batch-mode dependency:go-offline dependency:resolve-plugins\n", PrefetchScript(bs))
+	bs.PrefetchGoals = []string{"verify", "-DskipTests"}
+	assert.Equal(t, "#!/bin/sh\nset -xe\ncd .\nmvn --batch-mode verify -DskipTests\n", PrefetchScript(bs))
+	assert.True(t, bs.Offline)
+}
+
+func TestOfflineScriptRun(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("no shell available")
+	}
+	bin := t.TempDir()
+	// the fake mvn fails like maven does when an artifact was not prefetched
+	require.NoError(t, os.WriteFile(filepath.Join(bin, "mvn"), []byte(`#!/bin/sh
+echo "mvn $*"
+case "$*" in *--offline*) [ -n "$FAIL" ] && { echo "Cannot access central in offline mode"; exit 3; } ;; esac
+exit 0
+`), 0o755))
+
+	bs := NewBuildScript(false, t.TempDir(), "localhost")
+	bs.Offline = true
+	run := func(fail string) (string, error) {
+		cmd := exec.Command("sh", "-c", Script(bs))
+		cmd.Env = append(os.Environ(), "PATH="+bin+":"+os.Getenv("PATH"), "FAIL="+fail)
+		out, err := cmd.CombinedOutput()
+		return string(out), err
+	}
+
+	out, err := run("")
+	require.NoError(t, err, out)
+	assert.Contains(t, out, "mvn --batch-mode --offline package\n")
+
+	out, err = run("true")
+	var exitErr *exec.ExitError
+	require.ErrorAs(t, err, &exitErr)
+	assert.Equal(t, 3, exitErr.ExitCode())
+	assert.Contains(t, out, "ERROR: the offline build tried to reach the network")
+}
@@ -0,0 +1,301 @@
+package maven
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/containifyci/engine-ci/pkg/container"
+)
+
+// Scopes of the local repository, from the broadest to the narrowest.
+const (
+	CacheShared  = "shared"
+	CacheProject = "project"
+	CacheBranch  = "branch"
+	CachePom     = "pom"
+)
+
+const (
+	// cacheScopes holds the local repositories of the scopes below the cache mount.
+	cacheScopes = "scopes"
+	// readOnlyRepository is the local repository of read-only builds, it is
+	// discarded with the build container.
+	readOnlyRepository = "/tmp/containifyci/repository"
+)
+
+var unsafeKeyChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// CacheScope selects the local repository of the build below the cache
+// mount. Artifacts missing in it are read from the repositories of the broader
+// scopes with the chained local repository of Maven 3.9.
+type CacheScope struct {
+	Mode string
+	// Key is the folder of the scope below scopes/, empty for the shared repository.
+	Key string
+	// Fallbacks are the keys of the broader scopes that exist, narrowest first.
+	Fallbacks []string
+	// ReadOnly builds resolve into a repository of the build container and
+	// only read the cache.
+	ReadOnly bool
+}
+
+// GetCacheScope reads the cache_scope and cache_read_only properties, with
+// cache_branch naming the branch instead of the checked out one. cacheFolder
+// is the host folder of the cache mount, the fallbacks are the broader scopes
+// found in it.
+func GetCacheScope(build container.Build, cacheFolder string) (*CacheScope, error) {
+	scope := &CacheScope{
+		Mode:     strings.ToLower(build.Custom.String("cache_scope")),
+		ReadOnly: build.Custom.Bool("cache_read_only", false),
+	}
+	if scope.Mode == "" {
+		scope.Mode = CacheShared
+	}
+	if scope.Mode == CacheShared {
+		return scope, nil
+	}
+
+	project, err := projectKey(build.Folder)
+	if err != nil {
+		return nil, err
+	}
+	var broader []string
+	switch scope.Mode {
+	case CacheProject:
+		scope.Key = project
+	case CacheBranch:
+		branch, err := currentBranch(build)
+		if err != nil {
+			return nil, err
+		}
+		if branch == "" {
+			slog.Warn("No branch checked out, using the project cache", "folder", build.Folder)
+			scope.Mode, scope.Key = CacheProject, project
+			break
+		}
+		scope.Key = project + "-branch-" + sanitizeKey(branch)
+		broader = []string{project}
+	case CachePom:
+		hash, err := PomHash(build.Folder)
+		if err != nil {
+			return nil, err
+		}
+		scope.Key = "pom-" + hash
+		broader = []string{project}
+	default:
+		return nil, fmt.Errorf("invalid cache_scope %q", scope.Mode)
+	}
+
+	for _, key := range broader {
+		if _, err := os.Stat(filepath.Join(cacheFolder, cacheScopes, key, "repository")); err == nil {
+			scope.Fallbacks = append(scope.Fallbacks, key)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(cacheFolder, "repository")); err == nil {
+		scope.Fallbacks = append(scope.Fallbacks, "")
+	}
+	return scope, nil
+}
+
+// Chained reports whether the build reads other repositories through
+// maven.repo.local.tail.
+func (s *CacheScope) Chained() bool {
+	return s.ReadOnly || len(s.Fallbacks) > 0
+}
+
+// SupportsChaining reports whether the Maven version reads
+// maven.repo.local.tail, which came with Maven 3.9. Older versions ignore it.
+func SupportsChaining(version string) bool {
+	major, rest, _ := strings.Cut(version, ".")
+	minor, _, _ := strings.Cut(rest, ".")
+	majorN, err := strconv.Atoi(major)
+	if err != nil {
+		// not a release version, e.g. a custom distribution
+		return true
+	}
+	minorN, _ := strconv.Atoi(minor)
+	return majorN > 3 || (majorN == 3 && minorN >= 9)
+}
+
+// Snapshot copies the parts of cacheFolder a read-only build reads, the
+// repositories of the scope and its fallbacks and the files shared by all
+// scopes, into a new temporary folder. It is mounted instead of the cache so
+// nothing the build writes reaches the cache, engine-ci has no read-only
+// mounts. The caller removes the folder after the build.
+func (s *CacheScope) Snapshot(cacheFolder string) (string, error) {
+	dir, err := os.MkdirTemp("", "containifyci-maven-cache-")
+	if err != nil {
+		return "", err
+	}
+	entries, err := os.ReadDir(cacheFolder)
+	if err != nil && !os.IsNotExist(err) {
+		_ = os.RemoveAll(dir)
+		return "", err
+	}
+	var folders []string
+	for _, entry := range entries {
+		if entry.Name() != cacheScopes {
+			folders = append(folders, entry.Name())
+		}
+	}
+	for _, key := range append([]string{s.Key}, s.Fallbacks...) {
+		if key != "" {
+			folders = append(folders, filepath.Join(cacheScopes, key, "repository"))
+		}
+	}
+	for _, folder := range folders {
+		if err := copyTree(filepath.Join(cacheFolder, folder), filepath.Join(dir, folder)); err != nil {
+			_ = os.RemoveAll(dir)
+			return "", fmt.Errorf("failed to copy the maven cache: %w", err)
+		}
+	}
+	return dir, nil
+}
+
+// copyTree copies the files, folders and symlinks below src to dst, a
+// missing src copies nothing.
+func copyTree(src, dst string) error {
+	if _, err := os.Lstat(src); os.IsNotExist(err) {
+		return nil
+	}
+	return filepath.WalkDir(src, func(file string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, file)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		switch {
+		case entry.IsDir():
+			return os.MkdirAll(target, 0o755)
+		case entry.Type()&fs.ModeSymlink != 0:
+			link, err := os.Readlink(file)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		case entry.Type().IsRegular():
+			return copyFile(file, target)
+		}
+		// sockets and devices are no part of a cache
+		return nil
+	})
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// projectKey names the scope of the project in folder by the folder name and
+// a hash of its absolute path.
+func projectKey(folder string) (string, error) {
+	abs, err := filepath.Abs(folder)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(abs))
+	return "project-" + sanitizeKey(filepath.Base(abs)) + "-" + hex.EncodeToString(sum[:])[:12], nil
+}
+
+func sanitizeKey(value string) string {
+	return strings.Trim(unsafeKeyChars.ReplaceAllString(value, "-"), "-.")
+}
+
+func currentBranch(build container.Build) (string, error) {
+	if branch := build.Custom.String("cache_branch"); branch != "" {
+		return branch, nil
+	}
+	out, err := git(build.Folder, "rev-parse", "--abbrev-ref", "HEAD")
+	if err != nil {
+		return "", err
+	}
+	branch := strings.TrimSpace(out)
+	if branch == "HEAD" {
+		// detached, e.g. a CI checkout of a commit
+		return "", nil
+	}
+	return branch, nil
+}
+
+// PomHash hashes the pom.xml files of the project in folder and of all its
+// reactor modules, so the scope changes with any dependency change.
+func PomHash(folder string) (string, error) {
+	modules, err := ReactorModules(folder)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	for _, module := range append([]string{"."}, modules...) {
+		file := path.Join(module, PomFile)
+		data, err := os.ReadFile(filepath.Join(folder, file))
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(h, "%s\x00%d\x00", file, len(data))
+		h.Write(data)
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16], nil
+}
+
+// LocalRepository is the local repository of the scope key in the build container.
+func LocalRepository(key string) string {
+	if key == "" {
+		return CacheLocation + "repository"
+	}
+	return CacheLocation + path.Join(cacheScopes, key, "repository")
+}
+
+// Properties point Maven to the local repository of the scope and chain the
+// broader ones behind it. The shared scope keeps the defaults.
+func (s *CacheScope) Properties() []string {
+	head := LocalRepository(s.Key)
+	var tail []string
+	for _, key := range s.Fallbacks {
+		tail = append(tail, LocalRepository(key))
+	}
+	if s.ReadOnly {
+		tail = append([]string{head}, tail...)
+		head = readOnlyRepository
+	}
+
+	if head == LocalRepository("") {
+		return nil
+	}
+	props := []string{"maven.repo.local=" + head}
+	if len(tail) > 0 {
+		props = append(props, "maven.repo.local.tail="+strings.Join(tail, ","))
+	}
+	return props
+}
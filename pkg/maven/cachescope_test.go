@@ -0,0 +1,164 @@
+package maven
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetCacheScopeShared(t *testing.T) {
+	build := InitTest(t)
+
+	scope, err := GetCacheScope(*build, t.TempDir())
+	require.NoError(t, err)
+	assert.Equal(t, &CacheScope{Mode: CacheShared}, scope)
+	assert.Nil(t, scope.Properties())
+
+	build.Custom["cache_read_only"] = []string{"true"}
+	scope, err = GetCacheScope(*build, t.TempDir())
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"maven.repo.local=/tmp/containifyci/repository",
+		"maven.repo.local.tail=/root/.m2/repository",
+	}, scope.Properties())
+
+	build.Custom["cache_scope"] = []string{"global"}
+	_, err = GetCacheScope(*build, t.TempDir())
+	assert.Error(t, err)
+}
+
+func TestGetCacheScopeProject(t *testing.T) {
+	build := InitTest(t)
+	build.Folder = writeReactor(t)
+	build.Custom["cache_scope"] = []string{"project"}
+	cache := t.TempDir()
+
+	scope, err := GetCacheScope(*build, cache)
+	require.NoError(t, err)
+	key, err := projectKey(build.Folder)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(key, "project-001-"), key)
+	assert.Equal(t, &CacheScope{Mode: CacheProject, Key: key}, scope)
+	assert.Equal(t, []string{"maven.repo.local=/root/.m2/scopes/" + key + "/repository"}, scope.Properties())
+
+	// the shared repository is read when the project misses an artifact
+	require.NoError(t, os.MkdirAll(filepath.Join(cache, "repository"), 0o755))
+	scope, err = GetCacheScope(*build, cache)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"maven.repo.local=/root/.m2/scopes/" + key + "/repository",
+		"maven.repo.local.tail=/root/.m2/repository",
+	}, scope.Properties())
+
+	scope.ReadOnly = true
+	assert.Equal(t, []string{
+		"maven.repo.local=/tmp/containifyci/repository",
+		"maven.repo.local.tail=/root/.m2/scopes/" + key + "/repository,/root/.m2/repository",
+	}, scope.Properties())
+}
+
+func TestGetCacheScopeBranch(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	build := InitTest(t)
+	build.Folder = writeReactor(t)
+	build.Custom["cache_scope"] = []string{"branch"}
+	cache := t.TempDir()
+	key, err := projectKey(build.Folder)
+	require.NoError(t, err)
+	require.NoError(t, os.MkdirAll(filepath.Join(cache, "scopes", key, "repository"), 0o755))
+
+	runGit(t, build.Folder, "init", "-q", "-b", "feature/login")
+	runGit(t, build.Folder, "add", ".")
+	runGit(t, build.Folder, "commit", "-q", "-m", "init")
+	scope, err := GetCacheScope(*build, cache)
+	require.NoError(t, err)
+	assert.Equal(t, &CacheScope{Mode: CacheBranch, Key: key + "-branch-feature-login", Fallbacks: []string{key}}, scope)
+	assert.Equal(t, []string{
+		"maven.repo.local=/root/.m2/scopes/" + key + "-branch-feature-login/repository",
+		"maven.repo.local.tail=/root/.m2/scopes/" + key + "/repository",
+	}, scope.Properties())
+
+	build.Custom["cache_branch"] = []string{"release/1.x"}
+	scope, err = GetCacheScope(*build, cache)
+	require.NoError(t, err)
+	assert.Equal(t, key+"-branch-release-1.x", scope.Key)
+
+	// a detached checkout has no branch to scope by
+	delete(build.Custom, "cache_branch")
+	runGit(t, build.Folder, "checkout", "-q", "--detach")
+	scope, err = GetCacheScope(*build, cache)
+	require.NoError(t, err)
+	assert.Equal(t, CacheProject, scope.Mode)
+	assert.Equal(t, key, scope.Key)
+}
+
+func TestGetCacheScopePom(t *testing.T) {
+	build := InitTest(t)
+	build.Folder = writeReactor(t)
+	build.Custom["cache_scope"] = []string{"pom"}
+
+	scope, err := GetCacheScope(*build, t.TempDir())
+	require.NoError(t, err)
+	assert.Regexp(t, `^pom-[0-9a-f]{16}$`, scope.Key)
+
+	// a dependency change in a module selects another scope
+	writePom(t, filepath.Join(build.Folder, "services/orders"), `<project><dependencies/></project>`)
+	changed, err := GetCacheScope(*build, t.TempDir())
+	require.NoError(t, err)
+	assert.NotEqual(t, scope.Key, changed.Key)
+}
+
+func TestCacheScopeSnapshot(t *testing.T) {
+	cache := t.TempDir()
+	for file, data := range map[string]string{
+		"settings.xml": "<settings/>",
+		"repository/org/example/lib/1.0/lib-1.0.jar":                    "shared",
+		"scopes/project-app/repository/org/example/lib/1.0/lib-1.0.jar": "project",
+		"scopes/project-other/repository/org/example/x/1.0/x-1.0.jar":   "other",
+	} {
+		require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(cache, file)), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(cache, file), []byte(data), 0o644))
+	}
+	scope := &CacheScope{Mode: CacheBranch, Key: "project-app-branch-main", Fallbacks: []string{"project-app", ""}, ReadOnly: true}
+
+	snapshot, err := scope.Snapshot(cache)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = os.RemoveAll(snapshot) })
+	assert.FileExists(t, filepath.Join(snapshot, "settings.xml"))
+	assert.FileExists(t, filepath.Join(snapshot, "repository/org/example/lib/1.0/lib-1.0.jar"))
+	data, err := os.ReadFile(filepath.Join(snapshot, "scopes/project-app/repository/org/example/lib/1.0/lib-1.0.jar"))
+	require.NoError(t, err)
+	assert.Equal(t, "project", string(data))
+	// the repositories of other scopes are not read
+	assert.NoDirExists(t, filepath.Join(snapshot, "scopes/project-other"))
+
+	// the build writes to the copy only
+	require.NoError(t, os.WriteFile(filepath.Join(snapshot, "settings.xml"), []byte("changed"), 0o644))
+	data, err = os.ReadFile(filepath.Join(cache, "settings.xml"))
+	require.NoError(t, err)
+	assert.Equal(t, "<settings/>", string(data))
+}
+
+func TestSupportsChaining(t *testing.T) {
+	assert.True(t, SupportsChaining("3.9.0"))
+	assert.True(t, SupportsChaining("4.0.0"))
+	assert.False(t, SupportsChaining("3.8.8"))
+	assert.False(t, SupportsChaining("2.2.1"))
+	assert.True(t, SupportsChaining(""))
+
+	assert.False(t, (&CacheScope{Mode: CacheProject, Key: "project-app"}).Chained())
+	assert.True(t, (&CacheScope{Mode: CacheShared, ReadOnly: true}).Chained())
+	assert.True(t, (&CacheScope{Mode: CacheProject, Key: "project-app", Fallbacks: []string{""}}).Chained())
+}
+
+func TestSanitizeKey(t *testing.T) {
+	assert.Equal(t, "feature-JIRA-12-a_b.c", sanitizeKey("feature/JIRA-12 a_b.c"))
+	assert.Equal(t, "x", sanitizeKey("../x/"))
+}
@@ -0,0 +1,260 @@
+package maven
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/containifyci/engine-ci/pkg/container"
+)
+
+// CoverageReport is the JaCoCo XML report of a module below its target/.
+// Reports of the report-aggregate goal are not read, they would count the
+// covered modules twice.
+const CoverageReport = "site/jacoco/jacoco.xml"
+
+// Counter is a JaCoCo coverage counter, e.g. of type LINE or BRANCH.
+type Counter struct {
+	Type    string `xml:"type,attr"`
+	Missed  int    `xml:"missed,attr"`
+	Covered int    `xml:"covered,attr"`
+}
+
+// Add sums two counters.
+func (c Counter) Add(o Counter) Counter {
+	return Counter{Type: c.Type, Missed: c.Missed + o.Missed, Covered: c.Covered + o.Covered}
+}
+
+// Percent returns the covered share, 100 when there is nothing to cover.
+func (c Counter) Percent() float64 {
+	total := c.Missed + c.Covered
+	if total == 0 {
+		return 100
+	}
+	return float64(c.Covered) * 100 / float64(total)
+}
+
+// ModuleCoverage is the line and branch coverage of one reactor module.
+type ModuleCoverage struct {
+	Module string
+	Line   Counter
+	Branch Counter
+}
+
+type jacocoReport struct {
+	Name     string    `xml:"name,attr"`
+	Counters []Counter `xml:"counter"`
+}
+
+// ReadCoverage collects the JaCoCo reports of every module below folder
+// written since since, a zero since reads all reports.
+func ReadCoverage(folder string, since time.Time) ([]ModuleCoverage, error) {
+	files, err := findTargetFiles(folder, since, CoverageReport)
+	if err != nil {
+		return nil, err
+	}
+
+	var modules []ModuleCoverage
+	for _, file := range files {
+		data, err := os.ReadFile(file.Path)
+		if err != nil {
+			return nil, err
+		}
+		var report jacocoReport
+		if err := xml.Unmarshal(data, &report); err != nil {
+			slog.Warn("Skipping unreadable coverage report", "file", file.Path, "error", err)
+			continue
+		}
+		coverage := ModuleCoverage{Module: file.Module, Line: Counter{Type: "LINE"}, Branch: Counter{Type: "BRANCH"}}
+		for _, counter := range report.Counters {
+			switch counter.Type {
+			case "LINE":
+				coverage.Line = counter
+			case "BRANCH":
+				coverage.Branch = counter
+			}
+		}
+		modules = append(modules, coverage)
+	}
+	sort.Slice(modules, func(i, j int) bool { return modules[i].Module < modules[j].Module })
+	return modules, nil
+}
+
+// TotalCoverage sums the coverage of all modules. Modules the build did not
+// run, left out of the reactor or restored from the build cache, wrote no
+// report and are not part of it.
+func TotalCoverage(modules []ModuleCoverage) ModuleCoverage {
+	total := ModuleCoverage{Module: "total", Line: Counter{Type: "LINE"}, Branch: Counter{Type: "BRANCH"}}
+	for _, m := range modules {
+		total.Line = total.Line.Add(m.Line)
+		total.Branch = total.Branch.Add(m.Branch)
+	}
+	return total
+}
+
+// WriteCoverageTable prints the coverage of every module and the total.
+func WriteCoverageTable(w io.Writer, modules []ModuleCoverage) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "MODULE\tLINES\tLINE %\tBRANCHES\tBRANCH %")
+	for _, m := range append(modules[:len(modules):len(modules)], TotalCoverage(modules)) {
+		fmt.Fprintf(tw, "%s\t%d/%d\t%.1f\t%d/%d\t%.1f\n", m.Module,
+			m.Line.Covered, m.Line.Covered+m.Line.Missed, m.Line.Percent(),
+			m.Branch.Covered, m.Branch.Covered+m.Branch.Missed, m.Branch.Percent())
+	}
+	return tw.Flush()
+}
+
+// Thresholds are minimum coverage percentages, zero disables a check.
+type Thresholds struct {
+	Line   float64
+	Branch float64
+}
+
+// CoverageGate holds the thresholds for the total and for single modules.
+type CoverageGate struct {
+	Total   Thresholds
+	Modules map[string]Thresholds
+}
+
+// GetCoverageGate reads the thresholds from coverage_line and coverage_branch
+// for the total and coverage_line.<module> and coverage_branch.<module> for a
+// module. It returns nil when no threshold is set.
+func GetCoverageGate(build container.Build) (*CoverageGate, error) {
+	gate := &CoverageGate{Modules: map[string]Thresholds{}}
+	enabled := false
+	for key := range build.Custom {
+		kind, module, _ := strings.Cut(key, ".")
+		if kind != "coverage_line" && kind != "coverage_branch" {
+			continue
+		}
+		value := build.Custom.String(key)
+		percent, err := strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64)
+		if err != nil || percent < 0 || percent > 100 {
+			return nil, fmt.Errorf("invalid coverage threshold %s=%q", key, value)
+		}
+		enabled = true
+
+		thresholds := gate.Total
+		if module != "" {
+			thresholds = gate.Modules[module]
+		}
+		if kind == "coverage_line" {
+			thresholds.Line = percent
+		} else {
+			thresholds.Branch = percent
+		}
+		if module != "" {
+			gate.Modules[module] = thresholds
+		} else {
+			gate.Total = thresholds
+		}
+	}
+	if !enabled {
+		return nil, nil
+	}
+	return gate, nil
+}
+
+// Check returns a description of every threshold the coverage falls under.
+// The thresholds of the modules in unbuilt are skipped, they wrote no report.
+func (g *CoverageGate) Check(modules []ModuleCoverage, unbuilt map[string]bool) []string {
+	var violations []string
+	check := func(m ModuleCoverage, t Thresholds) {
+		if t.Line > 0 && m.Line.Percent() < t.Line {
+			violations = append(violations, fmt.Sprintf("%s line coverage %.1f%% is below %.1f%%", m.Module, m.Line.Percent(), t.Line))
+		}
+		if t.Branch > 0 && m.Branch.Percent() < t.Branch {
+			violations = append(violations, fmt.Sprintf("%s branch coverage %.1f%% is below %.1f%%", m.Module, m.Branch.Percent(), t.Branch))
+		}
+	}
+
+	check(TotalCoverage(modules), g.Total)
+	names := make([]string, 0, len(g.Modules))
+	for name := range g.Modules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if unbuilt[name] {
+			slog.Info("Skipping the coverage thresholds of a module the build did not run", "module", name)
+			continue
+		}
+		found := false
+		for _, m := range modules {
+			if m.Module == name {
+				check(m, g.Modules[name])
+				found = true
+			}
+		}
+		if !found {
+			violations = append(violations, fmt.Sprintf("%s has no coverage report", name))
+		}
+	}
+	return violations
+}
+
+// UnbuiltModules returns the modules with thresholds the build did not run
+// according to log, the ones Maven did not start and the ones restored from
+// the build cache. Modules are matched by the artifactId of their pom below
+// folder. Without a log every module counts as built.
+func (g *CoverageGate) UnbuiltModules(folder string, log *CacheReport) map[string]bool {
+	if log == nil {
+		return nil
+	}
+	built := map[string]bool{}
+	for _, module := range log.Built {
+		built[module] = true
+	}
+	unbuilt := map[string]bool{}
+	for name := range g.Modules {
+		pom, err := ReadPom(filepath.Join(folder, name, PomFile))
+		if err != nil {
+			// not a module of the reactor, its missing report is reported
+			continue
+		}
+		if !built[pom.ArtifactID] {
+			unbuilt[name] = true
+		}
+	}
+	return unbuilt
+}
+
+// ReportCoverage prints the coverage of the JaCoCo reports the build in
+// folder wrote since start and fails when it is under the thresholds of gate.
+// Thresholds of modules the build did not run are skipped.
+func ReportCoverage(w io.Writer, folder string, start time.Time, gate *CoverageGate) error {
+	modules, err := ReadCoverage(folder, start)
+	if err != nil {
+		return err
+	}
+	log, err := readBuildLog(folder)
+	if err != nil {
+		return err
+	}
+	if len(modules) == 0 {
+		// a build restoring every module from the build cache writes no report
+		restoredAll := log != nil && len(log.Built) == 0 && len(log.Restored) > 0
+		if gate != nil && !restoredAll {
+			return fmt.Errorf("coverage thresholds are set but no %s was found", CoverageReport)
+		}
+		return nil
+	}
+	if err := WriteCoverageTable(w, modules); err != nil {
+		return err
+	}
+	if gate == nil {
+		return nil
+	}
+	if violations := gate.Check(modules, gate.UnbuiltModules(folder, log)); len(violations) > 0 {
+		return fmt.Errorf("coverage gate failed: %s", strings.Join(violations, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+package maven
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/containifyci/engine-ci/pkg/container"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func jacocoXML(line, branch [2]int) string {
+	return fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<!DOCTYPE report PUBLIC "-//JACOCO//DTD Report 1.1//EN" "report.dtd">
+<report name="module">
+  <package name="com/example">
+    <counter type="LINE" missed="1000" covered="0"/>
+  </package>
+  <counter type="INSTRUCTION" missed="1" covered="1"/>
+  <counter type="LINE" missed="%d" covered="%d"/>
+  <counter type="BRANCH" missed="%d" covered="%d"/>
+</report>`, line[0], line[1], branch[0], branch[1])
+}
+
+func writeCoverage(t *testing.T, folder, module string, line, branch [2]int) {
+	writeReport(t, filepath.Join(folder, module, "target", filepath.Dir(CoverageReport)), filepath.Base(CoverageReport), jacocoXML(line, branch))
+}
+
+func TestReadCoverage(t *testing.T) {
+	folder := t.TempDir()
+	writeCoverage(t, folder, ".", [2]int{10, 90}, [2]int{0, 0})
+	writeCoverage(t, folder, "core", [2]int{50, 50}, [2]int{5, 15})
+	writeReport(t, filepath.Join(folder, "report/target/site/jacoco-aggregate"), "jacoco.xml", jacocoXML([2]int{60, 140}, [2]int{5, 15}))
+
+	modules, err := ReadCoverage(folder, time.Time{})
+	require.NoError(t, err)
+	assert.Equal(t, []ModuleCoverage{
+		{Module: ".", Line: Counter{Type: "LINE", Missed: 10, Covered: 90}, Branch: Counter{Type: "BRANCH"}},
+		{Module: "core", Line: Counter{Type: "LINE", Missed: 50, Covered: 50}, Branch: Counter{Type: "BRANCH", Missed: 5, Covered: 15}},
+	}, modules)
+
+	total := TotalCoverage(modules)
+	assert.InDelta(t, 70.0, total.Line.Percent(), 0.01)
+	assert.InDelta(t, 75.0, total.Branch.Percent(), 0.01)
+	assert.InDelta(t, 100.0, modules[0].Branch.Percent(), 0.01)
+
+	var table bytes.Buffer
+	require.NoError(t, WriteCoverageTable(&table, modules))
+	assert.Equal(t, `MODULE  LINES    LINE %  BRANCHES  BRANCH %
+.       90/100   90.0    0/0       100.0
+core    50/100   50.0    15/20     75.0
+total   140/200  70.0    15/20     75.0
+`, table.String())
+}
+
+func TestGetCoverageGate(t *testing.T) {
+	gate, err := GetCoverageGate(container.Build{Custom: container.Custom{"goals": {"verify"}}})
+	require.NoError(t, err)
+	assert.Nil(t, gate)
+
+	gate, err = GetCoverageGate(container.Build{Custom: container.Custom{
+		"coverage_line":             {"80"},
+		"coverage_branch.core":      {"60%"},
+		"coverage_line.api.v1":      {"90.5"},
+		"coverage_branch":           {"0"},
+		"coverage_line.core":        {"70"},
+		"coverage_report_something": {"x"},
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, &CoverageGate{
+		Total: Thresholds{Line: 80},
+		Modules: map[string]Thresholds{
+			"core":   {Line: 70, Branch: 60},
+			"api.v1": {Line: 90.5},
+		},
+	}, gate)
+
+	_, err = GetCoverageGate(container.Build{Custom: container.Custom{"coverage_line": {"high"}}})
+	assert.ErrorContains(t, err, "invalid coverage threshold")
+	_, err = GetCoverageGate(container.Build{Custom: container.Custom{"coverage_branch": {"101"}}})
+	assert.Error(t, err)
+}
+
+func TestReportCoverage(t *testing.T) {
+	folder := t.TempDir()
+	var out bytes.Buffer
+
+	assert.NoError(t, ReportCoverage(&out, folder, time.Time{}, nil))
+	assert.ErrorContains(t, ReportCoverage(&out, folder, time.Time{}, &CoverageGate{Total: Thresholds{Line: 50}}), "no site/jacoco/jacoco.xml")
+
+	writeCoverage(t, folder, "core", [2]int{20, 80}, [2]int{10, 10})
+	writeCoverage(t, folder, "web", [2]int{40, 60}, [2]int{0, 10})
+
+	assert.NoError(t, ReportCoverage(&out, folder, time.Time{}, &CoverageGate{Total: Thresholds{Line: 70, Branch: 60}}))
+	assert.Contains(t, out.String(), "total   140/200")
+
+	err := ReportCoverage(&out, folder, time.Time{}, &CoverageGate{
+		Total: Thresholds{Line: 75},
+		Modules: map[string]Thresholds{
+			"core":    {Branch: 60},
+			"web":     {Line: 50, Branch: 100},
+			"missing": {Line: 1},
+		},
+	})
+	assert.EqualError(t, err, "coverage gate failed: total line coverage 70.0% is below 75.0%; "+
+		"core branch coverage 50.0% is below 60.0%; missing has no coverage report")
+}
+
+func TestReportCoveragePartialBuild(t *testing.T) {
+	folder := t.TempDir()
+	writePom(t, filepath.Join(folder, "core"), `<project><artifactId>core</artifactId></project>`)
+	writePom(t, filepath.Join(folder, "web"), `<project><artifactId>web</artifactId></project>`)
+	writeCoverage(t, folder, "core", [2]int{20, 80}, [2]int{0, 0})
+	gate := &CoverageGate{Modules: map[string]Thresholds{"core": {Line: 50}, "web": {Line: 50}}}
+	var out bytes.Buffer
+
+	// without a log every threshold needs a report
+	assert.EqualError(t, ReportCoverage(&out, folder, time.Time{}, gate), "coverage gate failed: web has no coverage report")
+
+	// web was left out of the reactor
+	writeFile(t, filepath.Join(folder, BuildLog), "[INFO] ------------------------< com.example:core >------------------------\n")
+	assert.NoError(t, ReportCoverage(&out, folder, time.Time{}, gate))
+
+	// web was restored from the build cache
+	writeFile(t, filepath.Join(folder, BuildLog), `[INFO] ------------------------< com.example:core >------------------------
+[INFO] ------------------------< com.example:web >-------------------------
+[INFO] Found cached build, restoring com.example:web from cache by checksum 77ee
+`)
+	assert.NoError(t, ReportCoverage(&out, folder, time.Time{}, gate))
+
+	// web was built without writing its report
+	writeFile(t, filepath.Join(folder, BuildLog), `[INFO] ------------------------< com.example:core >------------------------
+[INFO] ------------------------< com.example:web >-------------------------
+`)
+	assert.EqualError(t, ReportCoverage(&out, folder, time.Time{}, gate), "coverage gate failed: web has no coverage report")
+
+	// every module was restored, no report was written
+	empty := t.TempDir()
+	writeFile(t, filepath.Join(empty, BuildLog), "[INFO] ------------------------< com.example:core >------------------------\n"+
+		"[INFO] Found cached build, restoring com.example:core from cache by checksum 8a3f\n")
+	assert.NoError(t, ReportCoverage(&out, empty, time.Time{}, &CoverageGate{Total: Thresholds{Line: 50}}))
+}
@@ -0,0 +1,306 @@
+package maven
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/containifyci/engine-ci/pkg/container"
+	"github.com/containifyci/engine-ci/pkg/cri/types"
+	"github.com/containifyci/engine-ci/pkg/cri/utils"
+	dockercontainer "github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+const (
+	// DaemonStateFolder is the folder of the cache folder recording the mvnd
+	// builder containers, one file per project.
+	DaemonStateFolder = "mvnd-containers"
+
+	DEFAULT_MVND_IDLE_TIMEOUT = 30 * time.Minute
+
+	daemonStorage  = "/tmp/containifyci/mvnd"
+	daemonActivity = "/tmp/containifyci/mvnd.activity"
+	daemonBusy     = "/tmp/containifyci/mvnd.busy"
+)
+
+// Daemon is the long-lived builder container of a project. The builds are
+// executed in it, so the warm mvnd daemons are reused across runs. The
+// container stops itself after IdleTimeout without a build.
+type Daemon struct {
+	Runtime     utils.RuntimeType
+	Name        string
+	Project     string
+	Image       string
+	IdleTimeout time.Duration
+	// StateFolder records the builder container created for the project.
+	StateFolder string
+
+	build container.Build
+}
+
+// DaemonState is the builder container engine-ci created for a project.
+type DaemonState struct {
+	ID      string `json:"id"`
+	Image   string `json:"image"`
+	Project string `json:"project"`
+	// Config is the checksum of the container options the builder container
+	// was created with, see daemonConfig.
+	Config string `json:"config"`
+}
+
+// GetDaemon reads the mvnd and mvnd_idle_timeout properties. It returns nil
+// unless mvnd is enabled.
+func GetDaemon(build container.Build) (*Daemon, error) {
+	if !build.Custom.Bool("mvnd", false) {
+		return nil, nil
+	}
+	timeout := DEFAULT_MVND_IDLE_TIMEOUT
+	if v := build.Custom.String("mvnd_idle_timeout"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < time.Minute {
+			return nil, fmt.Errorf("invalid mvnd_idle_timeout %q", v)
+		}
+		timeout = d
+	}
+	project, err := filepath.Abs(".")
+	if err != nil {
+		return nil, err
+	}
+	return &Daemon{
+		Runtime:     build.Runtime,
+		Name:        DaemonName(project),
+		Project:     project,
+		Image:       MavenImage(build),
+		IdleTimeout: timeout,
+		StateFolder: DaemonStates(),
+		build:       build,
+	}, nil
+}
+
+// DaemonStates returns the folder recording the mvnd builder containers.
+func DaemonStates() string {
+	return filepath.Join(CacheFolder(), DaemonStateFolder)
+}
+
+// DaemonName is the name of the builder container of the project folder.
+func DaemonName(project string) string {
+	sum := sha256.Sum256([]byte(project))
+	return "containifyci-mvnd-" + hex.EncodeToString(sum[:])[:12]
+}
+
+func (d *Daemon) stateFile() string {
+	return filepath.Join(d.StateFolder, d.Name+".json")
+}
+
+func readDaemonState(file string) (*DaemonState, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var state DaemonState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("invalid mvnd builder container state %s: %w", file, err)
+	}
+	return &state, nil
+}
+
+func writeDaemonState(file string, state DaemonState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(file, data, 0o644)
+}
+
+// daemonConfig is the checksum of the options a builder container created
+// from opts keeps for its lifetime: the mounts, limits and user. The
+// environment is part of it as well, the daemons started by the first build
+// inherit it. The checksum keeps the credentials out of the state file.
+func daemonConfig(opts types.ContainerConfig) string {
+	env := slices.Clone(opts.Env)
+	slices.Sort(env)
+	data, _ := json.Marshal(struct {
+		Image      string
+		WorkingDir string
+		Volumes    []types.Volume
+		Memory     int64
+		CPU        uint64
+		User       string
+		Env        []string
+	}{opts.Image, opts.WorkingDir, opts.Volumes, opts.Memory, opts.CPU, opts.User, env})
+	return ComputeChecksum(data)
+}
+
+// Build runs the script of opts in the builder container, starting it first
+// when it is not running or was started from another builder image or with
+// other options.
+func (d *Daemon) Build(opts types.ContainerConfig) error {
+	cli, err := runtimeClient(d.Runtime)
+	if err != nil {
+		return fmt.Errorf("failed to connect to the container runtime: %w", err)
+	}
+	defer cli.Close()
+
+	id, err := d.Ensure(cli, opts)
+	if err != nil {
+		return err
+	}
+	return d.Exec(cli, id, opts.Script, opts.Env)
+}
+
+// Ensure returns the builder container, which engine-ci creates from opts
+// unless it is already running the image with the same options.
+func (d *Daemon) Ensure(cli Runtime, opts types.ContainerConfig) (string, error) {
+	ctx := context.Background()
+	config := daemonConfig(opts)
+	state, err := readDaemonState(d.stateFile())
+	if err != nil {
+		return "", err
+	}
+	if state != nil {
+		inspect, err := cli.ContainerInspect(ctx, state.ID)
+		running := err == nil && inspect.ContainerJSONBase != nil && inspect.State != nil && inspect.State.Running
+		if running && state.Image == d.Image && state.Config == config {
+			slog.Info("Reusing mvnd builder container", "container", state.ID)
+			return state.ID, nil
+		}
+		slog.Info("Replacing mvnd builder container", "container", state.ID, "running", running, "image_changed", state.Image != d.Image, "options_changed", state.Config != config)
+		if err == nil {
+			if err := cli.ContainerRemove(ctx, state.ID, dockercontainer.RemoveOptions{Force: true}); err != nil {
+				return "", err
+			}
+		}
+	}
+
+	// the environment is handed to every build by Exec, so the credentials
+	// are not kept in the container configuration
+	opts.Env = nil
+	opts.Script = ""
+	opts.Cmd = []string{"sh", "-c", d.watchdog()}
+	builder := container.New(d.build)
+	slog.Info("Starting mvnd builder container", "container", d.Name, "idle_timeout", d.IdleTimeout)
+	if err := builder.Create(opts); err != nil {
+		return "", err
+	}
+	if err := builder.Start(); err != nil {
+		return "", err
+	}
+	return builder.ID, writeDaemonState(d.stateFile(), DaemonState{ID: builder.ID, Image: d.Image, Project: d.Project, Config: config})
+}
+
+// watchdog is the main process of the builder container. It stops the
+// daemons and so the container once no build ran for the idle timeout.
+func (d *Daemon) watchdog() string {
+	var sb strings.Builder
+	stop := fmt.Sprintf("mvnd -Dmvnd.daemonStorage=%s --stop || true", daemonStorage)
+	fmt.Fprintf(&sb, "mkdir -p %s\ntouch %s\n", daemonStorage, daemonActivity)
+	// the shell runs as pid 1 and ignores signals without a trap
+	fmt.Fprintf(&sb, "trap '%s; exit 0' TERM INT\n", stop)
+	fmt.Fprintf(&sb, "while [ -f %s ] || [ $(( $(date +%%s) - $(stat -c %%Y %s) )) -lt %d ]; do sleep 10 & wait $!; done\n",
+		daemonBusy, daemonActivity, int(d.IdleTimeout.Seconds()))
+	sb.WriteString(stop + "\n")
+	return sb.String()
+}
+
+// Exec runs script in the builder container id with the NAME=value pairs of
+// env and copies its output to stdout and stderr.
+func (d *Daemon) Exec(cli Runtime, id, script string, env []string) error {
+	ctx := context.Background()
+	track := fmt.Sprintf("touch %s; trap 'rm -f %s; touch %s' EXIT; sh -s; exit $?", daemonBusy, daemonBusy, daemonActivity)
+	exec, err := cli.ContainerExecCreate(ctx, id, dockercontainer.ExecOptions{
+		Cmd:          []string{"sh", "-c", track},
+		Env:          env,
+		AttachStdin:  true,
+		AttachStdout: true,
+		AttachStderr: true,
+	})
+	if err != nil {
+		return err
+	}
+	attach, err := cli.ContainerExecAttach(ctx, exec.ID, dockercontainer.ExecAttachOptions{})
+	if err != nil {
+		return err
+	}
+	defer attach.Close()
+
+	sent := make(chan struct{})
+	go func() {
+		defer close(sent)
+		if _, err := io.Copy(attach.Conn, strings.NewReader(script)); err != nil {
+			slog.Warn("Failed to send the build script to the mvnd builder container", "error", err)
+		}
+		_ = attach.CloseWrite()
+	}()
+	if _, err := stdcopy.StdCopy(os.Stdout, os.Stderr, attach.Reader); err != nil {
+		return err
+	}
+	<-sent
+
+	inspect, err := cli.ContainerExecInspect(ctx, exec.ID)
+	if err != nil {
+		return err
+	}
+	if inspect.ExitCode != 0 {
+		return fmt.Errorf("build in mvnd builder container %s exited with status %d", id, inspect.ExitCode)
+	}
+	return nil
+}
+
+// StopDaemons stops and removes the mvnd builder containers recorded in
+// stateFolder for project, for all projects when project is empty. It
+// returns the number of stopped containers.
+func StopDaemons(runtime utils.RuntimeType, stateFolder, project string) (int, error) {
+	files, err := filepath.Glob(filepath.Join(stateFolder, "*.json"))
+	if err != nil || len(files) == 0 {
+		return 0, err
+	}
+	cli, err := runtimeClient(runtime)
+	if err != nil {
+		return 0, fmt.Errorf("failed to connect to the container runtime: %w", err)
+	}
+	defer cli.Close()
+
+	ctx := context.Background()
+	stopped := 0
+	for _, file := range files {
+		state, err := readDaemonState(file)
+		if err != nil {
+			return stopped, err
+		}
+		if state == nil || (project != "" && state.Project != project) {
+			continue
+		}
+		if inspect, err := cli.ContainerInspect(ctx, state.ID); err == nil {
+			if inspect.ContainerJSONBase != nil && inspect.State != nil && inspect.State.Running {
+				// the watchdog stops the daemons on SIGTERM
+				if err := cli.ContainerStop(ctx, state.ID, dockercontainer.StopOptions{}); err != nil {
+					return stopped, err
+				}
+				stopped++
+			}
+			if err := cli.ContainerRemove(ctx, state.ID, dockercontainer.RemoveOptions{Force: true}); err != nil {
+				return stopped, err
+			}
+		}
+		if err := os.Remove(file); err != nil {
+			return stopped, err
+		}
+	}
+	return stopped, nil
+}
@@ -0,0 +1,194 @@
+package maven
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/containifyci/engine-ci/pkg/cri"
+	"github.com/containifyci/engine-ci/pkg/cri/critest"
+	"github.com/containifyci/engine-ci/pkg/cri/types"
+	"github.com/containifyci/engine-ci/pkg/cri/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetDaemon(t *testing.T) {
+	build := InitTest(t)
+
+	daemon, err := GetDaemon(*build)
+	require.NoError(t, err)
+	assert.Nil(t, daemon)
+
+	build.Custom["mvnd"] = []string{"true"}
+	daemon, err = GetDaemon(*build)
+	require.NoError(t, err)
+	project, _ := filepath.Abs(".")
+	assert.Equal(t, build.Runtime, daemon.Runtime)
+	assert.Equal(t, project, daemon.Project)
+	assert.Equal(t, DaemonName(project), daemon.Name)
+	assert.Equal(t, MavenImage(*build), daemon.Image)
+	assert.Equal(t, DEFAULT_MVND_IDLE_TIMEOUT, daemon.IdleTimeout)
+	assert.Equal(t, filepath.Join(CacheFolder(), DaemonStateFolder), daemon.StateFolder)
+
+	build.Runtime = "podman"
+	build.Custom["mvnd_idle_timeout"] = []string{"2h"}
+	daemon, err = GetDaemon(*build)
+	require.NoError(t, err)
+	assert.Equal(t, utils.Podman, daemon.Runtime)
+	assert.Equal(t, 2*time.Hour, daemon.IdleTimeout)
+
+	build.Custom["mvnd_idle_timeout"] = []string{"10s"}
+	_, err = GetDaemon(*build)
+	assert.Error(t, err)
+}
+
+func TestDaemonName(t *testing.T) {
+	assert.Equal(t, DaemonName("/work/app"), DaemonName("/work/app"))
+	assert.NotEqual(t, DaemonName("/work/app"), DaemonName("/work/lib"))
+	assert.True(t, strings.HasPrefix(DaemonName("/work/app"), "containifyci-mvnd-"))
+}
+
+func TestDaemonEnsure(t *testing.T) {
+	build := InitTest(t)
+	engine := useFakeEngine(t)
+	engine.Running = map[string]bool{}
+	daemon := &Daemon{Name: "containifyci-mvnd-test", Project: "/work/app", Image: "maven:1", IdleTimeout: time.Minute, StateFolder: t.TempDir(), build: *build}
+	opts := types.ContainerConfig{
+		Image:      "maven:1",
+		WorkingDir: "/src",
+		Volumes:    []types.Volume{{Type: "bind", Source: "/work/app", Target: "/src"}},
+		Memory:     1 << 30,
+		CPU:        2048,
+		Env:        []string{"TOKEN=s3cr3t"},
+		Script:     "mvnd --batch-mode package\n",
+	}
+
+	id, err := daemon.Ensure(engine, opts)
+	require.NoError(t, err)
+	state, err := readDaemonState(daemon.stateFile())
+	require.NoError(t, err)
+	assert.Equal(t, &DaemonState{ID: id, Image: "maven:1", Project: "/work/app", Config: daemonConfig(opts)}, state)
+	assert.NotContains(t, state.Config, "s3cr3t")
+
+	cRuntime, err := cri.InitContainerRuntime()
+	assert.NoError(t, err)
+	if v, ok := cRuntime.(*critest.MockContainerManager); ok {
+		// engine-ci creates the container from the build options
+		created := v.GetContainerByImage("maven:1")
+		require.Len(t, created.Opts.Cmd, 3)
+		assert.Contains(t, created.Opts.Cmd[2], "-lt 60 ]")
+		assert.Equal(t, opts.Volumes, created.Opts.Volumes)
+		assert.Equal(t, opts.Memory, created.Opts.Memory)
+		assert.Empty(t, created.Opts.Env)
+	}
+
+	// a running container of the same image is reused
+	engine.Running[id] = true
+	reused, err := daemon.Ensure(engine, opts)
+	require.NoError(t, err)
+	assert.Equal(t, id, reused)
+	assert.Empty(t, engine.Removed)
+
+	// the order of the environment does not matter
+	opts.Env = []string{"B=2", "TOKEN=s3cr3t"}
+	again, err := daemon.Ensure(engine, opts)
+	require.NoError(t, err)
+	assert.Equal(t, []string{id}, engine.Removed)
+	engine.Running[again] = true
+	opts.Env = []string{"TOKEN=s3cr3t", "B=2"}
+	reused, err = daemon.Ensure(engine, opts)
+	require.NoError(t, err)
+	assert.Equal(t, again, reused)
+	removed := len(engine.Removed)
+	assert.Equal(t, 1, removed)
+
+	// a container created with other mounts, limits or environment is replaced
+	changes := []func(opts *types.ContainerConfig){
+		func(opts *types.ContainerConfig) {
+			opts.Volumes = append(slices.Clone(opts.Volumes), types.Volume{Type: "bind", Source: "/cache", Target: "/root/.m2"})
+		},
+		func(opts *types.ContainerConfig) { opts.Memory = 2 << 30 },
+		func(opts *types.ContainerConfig) { opts.CPU = 4096 },
+		func(opts *types.ContainerConfig) { opts.Env = append(slices.Clone(opts.Env), "MAVEN_OPTS=-Xmx1g") },
+	}
+	for _, change := range changes {
+		changed := opts
+		change(&changed)
+		prev := again
+		again, err = daemon.Ensure(engine, changed)
+		require.NoError(t, err)
+		removed++
+		assert.Len(t, engine.Removed, removed)
+		assert.Equal(t, prev, engine.Removed[removed-1])
+		engine.Running[again] = true
+		opts = changed
+	}
+
+	// a container of another builder image is replaced
+	daemon.Image = "maven:2"
+	_, err = daemon.Ensure(engine, opts)
+	require.NoError(t, err)
+	assert.Equal(t, again, engine.Removed[len(engine.Removed)-1])
+	state, err = readDaemonState(daemon.stateFile())
+	require.NoError(t, err)
+	assert.Equal(t, "maven:2", state.Image)
+}
+
+func TestDaemonExec(t *testing.T) {
+	engine := useFakeEngine(t)
+	engine.Logs = "BUILD SUCCESS\n"
+	daemon := &Daemon{Name: "containifyci-mvnd-test"}
+
+	stdout := os.Stdout
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	os.Stdout = w
+	err = daemon.Exec(engine, "c1", "mvnd --batch-mode package\n", []string{"TOKEN=s3cr3t"})
+	os.Stdout = stdout
+	require.NoError(t, w.Close())
+	require.NoError(t, err)
+	out, err := io.ReadAll(r)
+	require.NoError(t, err)
+
+	assert.Equal(t, "BUILD SUCCESS\n", string(out))
+	assert.Equal(t, "mvnd --batch-mode package\n", engine.Stdin.String())
+	require.Len(t, engine.Execs, 1)
+	assert.Equal(t, []string{"TOKEN=s3cr3t"}, engine.Execs[0].Env)
+	assert.Equal(t, "sh", engine.Execs[0].Cmd[0])
+	assert.Contains(t, engine.Execs[0].Cmd[2], "sh -s")
+
+	engine.Logs = ""
+	engine.ExitCode = 1
+	err = daemon.Exec(engine, "c1", "mvnd --batch-mode package\n", nil)
+	assert.EqualError(t, err, "build in mvnd builder container c1 exited with status 1")
+}
+
+func TestStopDaemons(t *testing.T) {
+	engine := useFakeEngine(t)
+	engine.Running = map[string]bool{"c1": true, "c2": true}
+	folder := t.TempDir()
+	require.NoError(t, writeDaemonState(filepath.Join(folder, "app.json"), DaemonState{ID: "c1", Project: "/work/app"}))
+	require.NoError(t, writeDaemonState(filepath.Join(folder, "lib.json"), DaemonState{ID: "c2", Project: "/work/lib"}))
+
+	stopped, err := StopDaemons(utils.Docker, folder, "/work/app")
+	require.NoError(t, err)
+	assert.Equal(t, 1, stopped)
+	assert.Equal(t, []string{"c1"}, engine.Stopped)
+	assert.Equal(t, []string{"c1"}, engine.Removed)
+	assert.NoFileExists(t, filepath.Join(folder, "app.json"))
+
+	stopped, err = StopDaemons(utils.Docker, folder, "")
+	require.NoError(t, err)
+	assert.Equal(t, 1, stopped)
+	assert.Equal(t, []string{"c1", "c2"}, engine.Removed)
+	assert.NoFileExists(t, filepath.Join(folder, "lib.json"))
+
+	stopped, err = StopDaemons(utils.Docker, folder, "")
+	require.NoError(t, err)
+	assert.Zero(t, stopped)
+}
@@ -0,0 +1,110 @@
+package maven
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/containifyci/engine-ci/pkg/container"
+)
+
+const (
+	DEFAULT_MAVEN_DIST_VERSION = "3.9.11"
+	DEFAULT_DISTRO             = "jammy"
+	DEFAULT_MVND_VERSION       = "1.0.2"
+)
+
+// DockerfileArgs parameterize the builder Dockerfile template.
+type DockerfileArgs struct {
+	JDK            int
+	BaseImage      string
+	PackageManager string
+	MavenVersion   string
+	MavenMajor     string
+	Distro         string
+	// MirrorURL replaces the Apache download site for the Maven distribution.
+	MirrorURL string
+	// MvndVersion installs the Maven Daemon when set.
+	MvndVersion string
+}
+
+var (
+	dockerfileTemplate = template.Must(template.ParseFS(f, "Dockerfile.maven.tmpl"))
+	imageTagValue      = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+)
+
+// NewDockerfileArgs reads the builder settings from the build: the JDK version
+// from GetVersion, its distribution from jdk, the Maven version from
+// maven_version, the base distro from distro, the mirror from maven_mirror_url
+// and, with mvnd, the Maven Daemon version from mvnd_version.
+func NewDockerfileArgs(build container.Build) (DockerfileArgs, error) {
+	dist, err := GetJDK(build)
+	if err != nil {
+		return DockerfileArgs{}, err
+	}
+	version := GetVersion(build)
+	jdk, err := strconv.Atoi(strings.TrimPrefix(version, "v"))
+	if err != nil {
+		return DockerfileArgs{}, fmt.Errorf("invalid jdk version %q", version)
+	}
+	if !dist.Supports(version) {
+		return DockerfileArgs{}, fmt.Errorf("jdk %s is not available for version %s", dist.Name, version)
+	}
+
+	mavenVersion := build.Custom.String("maven_version")
+	if mavenVersion == "" {
+		mavenVersion = DEFAULT_MAVEN_DIST_VERSION
+	}
+	distro := build.Custom.String("distro")
+	if distro == "" {
+		distro = DEFAULT_DISTRO
+	}
+	mvndVersion := ""
+	if build.Custom.Bool("mvnd", false) {
+		mvndVersion = build.Custom.String("mvnd_version")
+		if mvndVersion == "" {
+			mvndVersion = DEFAULT_MVND_VERSION
+		}
+	}
+	for _, v := range []string{mavenVersion, distro, mvndVersion} {
+		if v == "" {
+			continue
+		}
+		if !imageTagValue.MatchString(v) {
+			return DockerfileArgs{}, fmt.Errorf("invalid builder image setting %q", v)
+		}
+	}
+
+	mirror, err := GetMirrorURL(build)
+	if err != nil {
+		return DockerfileArgs{}, err
+	}
+
+	major, _, _ := strings.Cut(mavenVersion, ".")
+	return DockerfileArgs{
+		JDK:            jdk,
+		BaseImage:      dist.BaseImage(jdk, distro),
+		PackageManager: dist.PackageManager,
+		MavenVersion:   mavenVersion,
+		MavenMajor:     major,
+		Distro:         distro,
+		MirrorURL:      mirror,
+		MvndVersion:    mvndVersion,
+	}, nil
+}
+
+// Dockerfile renders the builder Dockerfile for the build.
+func Dockerfile(build container.Build) ([]byte, error) {
+	args, err := NewDockerfileArgs(build)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := dockerfileTemplate.Execute(&buf, args); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,83 @@
+package maven
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDockerfileDefaults(t *testing.T) {
+	build := InitTest(t)
+
+	dockerFile, err := Dockerfile(*build)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(dockerFile), "FROM eclipse-temurin:17-jdk-jammy\n"))
+	assert.Contains(t, string(dockerFile), "ARG MAVEN_VERSION=3.9.11\n")
+	assert.Contains(t, string(dockerFile), "https://archive.apache.org/dist/maven/maven-3/${MAVEN_VERSION}/")
+	// the checksum tag of the rendered default is stable so existing builder images stay cached
+	assert.Equal(t, "15bd0eac7b3716edef701cd087d96645b33ef27c27191ffdbb9025eb24ed59ce", ComputeChecksum(dockerFile))
+}
+
+func TestDockerfileCustom(t *testing.T) {
+	build := InitTest(t)
+	build.Custom["from"] = []string{"v25"}
+	build.Custom["maven_version"] = []string{"4.0.0"}
+	build.Custom["distro"] = []string{"noble"}
+
+	dockerFile, err := Dockerfile(*build)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(dockerFile), "FROM eclipse-temurin:25-jdk-noble\n"))
+	assert.Contains(t, string(dockerFile), "ARG MAVEN_VERSION=4.0.0\n")
+	assert.Contains(t, string(dockerFile), "https://archive.apache.org/dist/maven/maven-4/${MAVEN_VERSION}/")
+	assert.True(t, Matches(*build))
+	assert.True(t, strings.HasPrefix(MavenImage(*build), "containifyci/maven-4.0.0-eclipse-temurin-v25:"))
+}
+
+func TestDockerfileInvalid(t *testing.T) {
+	build := InitTest(t)
+	build.Custom["distro"] = []string{"jammy\nRUN rm -rf /"}
+
+	_, err := Dockerfile(*build)
+	assert.Error(t, err)
+
+	build.Custom["from"] = []string{"latest"}
+	_, err = Dockerfile(*build)
+	assert.Error(t, err)
+}
+
+func TestDockerfileMirror(t *testing.T) {
+	build := InitTest(t)
+	build.Custom["maven_mirror_url"] = []string{"https://nexus.example.com/repository/maven-public/"}
+
+	dockerFile, err := Dockerfile(*build)
+	require.NoError(t, err)
+	assert.Contains(t, string(dockerFile), "RUN curl -fsSL https://nexus.example.com/repository/maven-public/org/apache/maven/apache-maven/${MAVEN_VERSION}/apache-maven-${MAVEN_VERSION}-bin.tar.gz \\\n")
+	assert.NotContains(t, string(dockerFile), "archive.apache.org/dist/maven/maven-")
+
+	build.Custom["maven_mirror_url"] = []string{"https://nexus.example.com/$(id)"}
+	_, err = Dockerfile(*build)
+	assert.Error(t, err)
+}
+
+func TestDockerfileMvnd(t *testing.T) {
+	build := InitTest(t)
+	build.Custom["mvnd"] = []string{"true"}
+
+	dockerFile, err := Dockerfile(*build)
+	require.NoError(t, err)
+	assert.Contains(t, string(dockerFile), "ARG MVND_VERSION=1.0.2\n")
+	assert.Contains(t, string(dockerFile), "https://archive.apache.org/dist/maven/mvnd/${MVND_VERSION}/maven-mvnd-${MVND_VERSION}-linux-${arch}.tar.gz")
+	assert.Contains(t, string(dockerFile), "ln -s /opt/mvnd/bin/mvnd /usr/local/bin/mvnd\n\nENV MAVEN_HOME")
+
+	build.Custom["maven_mirror_url"] = []string{"https://nexus.example.com/repository/maven-public"}
+	dockerFile, err = Dockerfile(*build)
+	require.NoError(t, err)
+	assert.Contains(t, string(dockerFile), "curl -fsSL https://nexus.example.com/repository/maven-public/org/apache/maven/daemon/mvnd/${MVND_VERSION}/mvnd-${MVND_VERSION}-linux-${arch}.tar.gz ")
+	assert.NotContains(t, string(dockerFile), "archive.apache.org")
+
+	build.Custom["mvnd_version"] = []string{"1.0.2 && rm -rf /"}
+	_, err = Dockerfile(*build)
+	assert.Error(t, err)
+}
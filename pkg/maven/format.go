@@ -0,0 +1,259 @@
+package maven
+
+import (
+	"bufio"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/containifyci/engine-ci/pkg/build"
+	"github.com/containifyci/engine-ci/pkg/container"
+)
+
+const (
+	SpotlessPlugin = "com.diffplug.spotless:spotless-maven-plugin"
+
+	GoogleJavaFormat            = "com.google.googlejavaformat:google-java-format"
+	DEFAULT_GOOGLE_JAVA_FORMAT  = "1.28.0"
+	googleJavaFormatJDK11       = "1.24.0"
+	googleJavaFormatJDK8        = "1.7"
+	googleJavaFormatJar         = "/tmp/google-java-format/google-java-format-all-deps.jar"
+	googleJavaFormatSourceFiles = "target/java-sources.txt"
+
+	// UnformattedFiles lists the files google-java-format would change, one per line.
+	UnformattedFiles = "target/unformatted-files.txt"
+)
+
+// googleJavaFormatExports open the javac internals google-java-format parses with.
+var googleJavaFormatExports = []string{
+	"--add-exports=jdk.compiler/com.sun.tools.javac.api=ALL-UNNAMED",
+	"--add-exports=jdk.compiler/com.sun.tools.javac.code=ALL-UNNAMED",
+	"--add-exports=jdk.compiler/com.sun.tools.javac.file=ALL-UNNAMED",
+	"--add-exports=jdk.compiler/com.sun.tools.javac.parser=ALL-UNNAMED",
+	"--add-exports=jdk.compiler/com.sun.tools.javac.tree=ALL-UNNAMED",
+	"--add-exports=jdk.compiler/com.sun.tools.javac.util=ALL-UNNAMED",
+}
+
+var googleJavaFormatVersion = regexp.MustCompile(`^\d+\.\d+(\.\d+)?$`)
+
+// GoogleJavaFormatJDK returns the lowest JDK the google-java-format version
+// runs on: 1.25 and later need JDK 17, 1.8 to 1.24 JDK 11.
+func GoogleJavaFormatJDK(version string) int {
+	major, rest, _ := strings.Cut(version, ".")
+	minor, _, _ := strings.Cut(rest, ".")
+	majorN, _ := strconv.Atoi(major)
+	minorN, _ := strconv.Atoi(minor)
+	switch {
+	case majorN > 1 || minorN >= 25:
+		return 17
+	case minorN >= 8:
+		return 11
+	default:
+		return 8
+	}
+}
+
+// GoogleJavaFormatVersion returns the google-java-format version for the
+// builder JDK, google_java_format_version or else the newest release running
+// on it. An explicit version the builder JDK cannot run is an error.
+func GoogleJavaFormatVersion(build container.Build) (string, error) {
+	jdk, err := strconv.Atoi(strings.TrimPrefix(GetVersion(build), "v"))
+	if err != nil {
+		return "", fmt.Errorf("invalid jdk version %q", GetVersion(build))
+	}
+	version := build.Custom.String("google_java_format_version")
+	if version == "" {
+		switch {
+		case jdk >= 17:
+			return DEFAULT_GOOGLE_JAVA_FORMAT, nil
+		case jdk >= 11:
+			return googleJavaFormatJDK11, nil
+		default:
+			return googleJavaFormatJDK8, nil
+		}
+	}
+	if !googleJavaFormatVersion.MatchString(version) {
+		return "", fmt.Errorf("invalid google_java_format_version %q", version)
+	}
+	if required := GoogleJavaFormatJDK(version); jdk < required {
+		return "", fmt.Errorf("google-java-format %s needs JDK %d or later, the builder runs JDK %d", version, required, jdk)
+	}
+	return version, nil
+}
+
+func NewFormat() build.BuildStep {
+	return build.Stepper{
+		RunFn: func(build container.Build) (string, error) {
+			container := new(&build)
+			return container.RunFormat()
+		},
+		MatchedFn: func(build container.Build) bool {
+			return build.Custom.Bool("format", false) && Matches(build)
+		},
+		ImagesFn: func(build container.Build) []string {
+			return []string{MavenImage(build)}
+		},
+		Name_:  "maven-format",
+		Async_: false,
+	}
+}
+
+// HasSpotless reports whether the pom of folder, or one of its local parents,
+// configures the Spotless plugin.
+func HasSpotless(folder string) bool {
+	pom, err := ReadPom(filepath.Join(folder, PomFile))
+	if err != nil {
+		return false
+	}
+	for _, plugin := range append(pom.Plugins, pom.Managed...) {
+		if plugin.GroupID+":"+plugin.ArtifactID == SpotlessPlugin {
+			return true
+		}
+	}
+	return false
+}
+
+// FormatScript checks the formatting of the sources, or rewrites them with
+// fix. Projects configuring Spotless run spotless:check or spotless:apply,
+// all others are checked with google-java-format.
+func (c *MavenContainer) FormatScript(fix bool) (string, error) {
+	bs := c.NewBuildScript()
+	// the prefetch does not cover the formatter plugins
+	bs.Offline = false
+	if HasSpotless(c.Folder) {
+		goal := "check"
+		if fix {
+			goal = "apply"
+		}
+		bs.Goals = []string{SpotlessPlugin + ":" + goal}
+		return Script(bs), nil
+	}
+
+	version, err := GoogleJavaFormatVersion(*c.GetBuild())
+	if err != nil {
+		return "", err
+	}
+	// the formatter is downloaded by the root project alone, a module
+	// selection or the build cache would not apply to it
+	bs.Reactor = nil
+	if bs.BuildCache != nil {
+		var props []string
+		for _, prop := range bs.Properties {
+			if !strings.HasPrefix(prop, "maven.build.cache.") {
+				props = append(props, prop)
+			}
+		}
+		bs.Properties = props
+		bs.BuildCache = nil
+	}
+	bs.Goals = []string{"dependency:copy"}
+	bs.Properties = append(bs.Properties[:len(bs.Properties):len(bs.Properties)],
+		fmt.Sprintf("artifact=%s:%s:jar:all-deps", GoogleJavaFormat, version),
+		"outputDirectory="+filepath.Dir(googleJavaFormatJar),
+		"mdep.stripVersion=true",
+	)
+	bs.Args = append(bs.Args[:len(bs.Args):len(bs.Args)], "--non-recursive")
+
+	java := []string{"java"}
+	// JDK 8 has no module system to export from
+	if GetVersion(*c.GetBuild()) != "v8" {
+		java = append(java, googleJavaFormatExports...)
+	}
+	java = append(java, "-jar", googleJavaFormatJar)
+	for i, arg := range java {
+		java[i] = shellQuote(arg)
+	}
+	run := strings.Join(java, " ")
+
+	var sb strings.Builder
+	sb.WriteString(Script(bs))
+	sb.WriteString("mkdir -p target\n")
+	fmt.Fprintf(&sb, "find . -name '*.java' -path '*/src/*' -not -path '*/target/*' -not -path '*/.*' | sort > %s\n", googleJavaFormatSourceFiles)
+	fmt.Fprintf(&sb, ": > %s\n", UnformattedFiles)
+	fmt.Fprintf(&sb, "if [ -s %s ]; then\n", googleJavaFormatSourceFiles)
+	fmt.Fprintf(&sb, "  %s --dry-run @%s > %s\n", run, googleJavaFormatSourceFiles, UnformattedFiles)
+	if fix {
+		fmt.Fprintf(&sb, "  %s --replace @%s\n", run, googleJavaFormatSourceFiles)
+	}
+	sb.WriteString("fi\n")
+	return sb.String(), nil
+}
+
+// ReadUnformatted returns the files google-java-format reported in folder.
+func ReadUnformatted(folder string) ([]string, error) {
+	fh, err := os.Open(filepath.Join(folder, UnformattedFiles))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer fh.Close()
+
+	var files []string
+	scanner := bufio.NewScanner(fh)
+	for scanner.Scan() {
+		if file := strings.TrimPrefix(strings.TrimSpace(scanner.Text()), "./"); file != "" {
+			files = append(files, file)
+		}
+	}
+	return files, scanner.Err()
+}
+
+func (c *MavenContainer) Format() error {
+	fix := c.GetBuild().Custom.Bool("format_fix", false)
+	script, err := c.FormatScript(fix)
+	if err != nil {
+		slog.Error("Failed to prepare format check", "error", err)
+		os.Exit(1)
+	}
+
+	// a stale report of a previous run must not be taken for this one
+	_ = os.Remove(filepath.Join(c.Folder, UnformattedFiles))
+
+	opts, cleanup := c.BuildOpts()
+	opts.Script = script
+	err = buildingContainer(c, opts)
+	cleanup()
+	if err != nil {
+		// Spotless lists the unformatted files itself
+		slog.Error("Format check failed", "error", err)
+		os.Exit(1)
+	}
+
+	files, err := ReadUnformatted(c.Folder)
+	if err != nil {
+		slog.Error("Failed to read format report", "error", err)
+		os.Exit(1)
+	}
+	for _, file := range files {
+		if fix {
+			slog.Info("Formatted file", "file", file)
+		} else {
+			slog.Error("File is not formatted", "file", file)
+		}
+	}
+	if len(files) > 0 && !fix {
+		slog.Error("Format check failed, run with format_fix to rewrite the files", "files", len(files))
+		os.Exit(1)
+	}
+	return nil
+}
+
+func (c *MavenContainer) RunFormat() (string, error) {
+	err := c.BuildMavenImage()
+	if err != nil {
+		slog.Error("Failed to build maven image", "error", err)
+		return "", err
+	}
+
+	err = c.Format()
+	if err != nil {
+		return "", err
+	}
+	return c.ID, nil
+}
@@ -0,0 +1,128 @@
+package maven
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const spotlessPom = `<project>
+  <build><plugins><plugin>
+    <groupId>com.diffplug.spotless</groupId>
+    <artifactId>spotless-maven-plugin</artifactId>
+  </plugin></plugins></build>
+</project>`
+
+func TestHasSpotless(t *testing.T) {
+	folder := t.TempDir()
+	assert.False(t, HasSpotless(folder))
+
+	writePom(t, folder, `<project><artifactId>app</artifactId></project>`)
+	assert.False(t, HasSpotless(folder))
+
+	writePom(t, folder, spotlessPom)
+	assert.True(t, HasSpotless(folder))
+}
+
+func TestFormatScriptSpotless(t *testing.T) {
+	arg := InitTest(t)
+	arg.Folder = t.TempDir()
+	writePom(t, arg.Folder, spotlessPom)
+
+	mc := new(arg)
+	script, err := mc.FormatScript(false)
+	require.NoError(t, err)
+	assert.Contains(t, script, "\nmvn --batch-mode --threads 2 com.diffplug.spotless:spotless-maven-plugin:check\n")
+
+	script, err = mc.FormatScript(true)
+	require.NoError(t, err)
+	assert.Contains(t, script, "\nmvn --batch-mode --threads 2 com.diffplug.spotless:spotless-maven-plugin:apply\n")
+}
+
+func TestFormatScriptGoogleJavaFormat(t *testing.T) {
+	arg := InitTest(t)
+	arg.Custom["maven_properties"] = []string{"revision=1"}
+
+	mc := new(arg)
+	script, err := mc.FormatScript(false)
+	require.NoError(t, err)
+	assert.Contains(t, script, "mvn --batch-mode --threads 2 dependency:copy -Drevision=1 -Dartifact=com.google.googlejavaformat:google-java-format:1.28.0:jar:all-deps -DoutputDirectory=/tmp/google-java-format -Dmdep.stripVersion=true --non-recursive\n")
+	assert.Contains(t, script, "-jar /tmp/google-java-format/google-java-format-all-deps.jar --dry-run @target/java-sources.txt > target/unformatted-files.txt\n")
+	assert.NotContains(t, script, "--replace")
+	assert.Equal(t, []string{"revision=1"}, arg.Custom["maven_properties"])
+
+	arg.Custom["google_java_format_version"] = []string{"1.22.0"}
+	script, err = mc.FormatScript(true)
+	require.NoError(t, err)
+	assert.Contains(t, script, "google-java-format:1.22.0:jar:all-deps")
+	assert.Contains(t, script, "--replace @target/java-sources.txt\n")
+
+	arg.Custom["google_java_format_version"] = []string{"1.22; rm -rf /"}
+	_, err = mc.FormatScript(false)
+	assert.Error(t, err)
+
+	arg.Custom["google_java_format_version"] = nil
+	arg.Custom["from"] = []string{"v8"}
+	script, err = mc.FormatScript(false)
+	require.NoError(t, err)
+	assert.Contains(t, script, "google-java-format:1.7:jar:all-deps")
+	assert.Contains(t, script, " java -jar /tmp/google-java-format/google-java-format-all-deps.jar --dry-run ")
+}
+
+func TestFormatScriptGoogleJavaFormatModules(t *testing.T) {
+	arg := InitTest(t)
+	arg.Folder = writeReactor(t)
+	arg.Custom["modules"] = []string{"core"}
+	arg.Custom["build_cache"] = []string{"true"}
+
+	mc := new(arg)
+	script, err := mc.FormatScript(false)
+	require.NoError(t, err)
+	// --non-recursive cannot be combined with a module selection
+	assert.Contains(t, script, "mvn --batch-mode --threads 2 dependency:copy -Dartifact=com.google.googlejavaformat:google-java-format:1.28.0:jar:all-deps -DoutputDirectory=/tmp/google-java-format -Dmdep.stripVersion=true --non-recursive\n")
+	assert.NotContains(t, script, "--projects")
+	assert.NotContains(t, script, "maven.build.cache")
+}
+
+func TestGoogleJavaFormatVersion(t *testing.T) {
+	arg := InitTest(t)
+	version, err := GoogleJavaFormatVersion(*arg)
+	require.NoError(t, err)
+	assert.Equal(t, "1.28.0", version)
+
+	arg.Custom["from"] = []string{"v11"}
+	version, err = GoogleJavaFormatVersion(*arg)
+	require.NoError(t, err)
+	assert.Equal(t, "1.24.0", version)
+
+	arg.Custom["from"] = []string{"v8"}
+	version, err = GoogleJavaFormatVersion(*arg)
+	require.NoError(t, err)
+	assert.Equal(t, "1.7", version)
+
+	arg.Custom["from"] = []string{"v11"}
+	arg.Custom["google_java_format_version"] = []string{"1.28.0"}
+	_, err = GoogleJavaFormatVersion(*arg)
+	assert.EqualError(t, err, "google-java-format 1.28.0 needs JDK 17 or later, the builder runs JDK 11")
+
+	arg.Custom["google_java_format_version"] = []string{"1.22.0"}
+	version, err = GoogleJavaFormatVersion(*arg)
+	require.NoError(t, err)
+	assert.Equal(t, "1.22.0", version)
+}
+
+func TestReadUnformatted(t *testing.T) {
+	folder := t.TempDir()
+	files, err := ReadUnformatted(folder)
+	require.NoError(t, err)
+	assert.Empty(t, files)
+
+	require.NoError(t, os.MkdirAll(filepath.Join(folder, "target"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(folder, UnformattedFiles), []byte("./core/src/main/java/A.java\n\nweb/src/main/java/B.java\n"), 0o644))
+	files, err = ReadUnformatted(folder)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"core/src/main/java/A.java", "web/src/main/java/B.java"}, files)
+}
@@ -0,0 +1,118 @@
+package maven
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/containifyci/engine-ci/pkg/container"
+)
+
+const DEFAULT_JDK = "temurin"
+
+// JDK is a vendor distribution the builder image can be based on.
+type JDK struct {
+	// Name is the value of the jdk property selecting the distribution.
+	Name string
+	// Vendor is part of the builder image name so images of different vendors never collide.
+	Vendor         string
+	PackageManager string
+	Versions       []int
+	image          func(version int, distro string) string
+	runtime        func(version int, distro string) string
+}
+
+// BaseImage returns the JDK image the builder is built from.
+func (j JDK) BaseImage(version int, distro string) string {
+	return j.image(version, distro)
+}
+
+// RuntimeImage returns the JRE image executable jars are shipped on.
+func (j JDK) RuntimeImage(version int, distro string) string {
+	return j.runtime(version, distro)
+}
+
+// JDKs are the supported distributions. Only eclipse-temurin honors the distro property.
+var JDKs = []JDK{
+	{
+		Name:           "temurin",
+		Vendor:         "eclipse-temurin",
+		PackageManager: "apt",
+		Versions:       []int{8, 11, 17, 21, 25},
+		image: func(version int, distro string) string {
+			return fmt.Sprintf("eclipse-temurin:%d-jdk-%s", version, distro)
+		},
+		runtime: func(version int, distro string) string {
+			return fmt.Sprintf("eclipse-temurin:%d-jre-%s", version, distro)
+		},
+	},
+	{
+		Name:           "corretto",
+		Vendor:         "amazon-corretto",
+		PackageManager: "dnf",
+		Versions:       []int{8, 11, 17, 21, 25},
+		image: func(version int, _ string) string {
+			return fmt.Sprintf("amazoncorretto:%d-al2023-jdk", version)
+		},
+		runtime: func(version int, _ string) string {
+			return fmt.Sprintf("amazoncorretto:%d-al2023-headless", version)
+		},
+	},
+	{
+		Name:           "zulu",
+		Vendor:         "azul-zulu",
+		PackageManager: "apt",
+		Versions:       []int{8, 11, 17, 21, 25},
+		image: func(version int, _ string) string {
+			return fmt.Sprintf("azul/zulu-openjdk:%d", version)
+		},
+		runtime: func(version int, _ string) string {
+			return fmt.Sprintf("azul/zulu-openjdk:%d-jre", version)
+		},
+	},
+	{
+		Name:           "liberica",
+		Vendor:         "bellsoft-liberica",
+		PackageManager: "apt",
+		Versions:       []int{8, 11, 17, 21, 25},
+		image: func(version int, _ string) string {
+			return fmt.Sprintf("bellsoft/liberica-openjdk-debian:%d", version)
+		},
+		runtime: func(version int, _ string) string {
+			return fmt.Sprintf("bellsoft/liberica-openjre-debian:%d", version)
+		},
+	},
+	{
+		Name:           "graalvm",
+		Vendor:         "graalvm-community",
+		PackageManager: "microdnf",
+		Versions:       []int{17, 21, 25},
+		image: func(version int, _ string) string {
+			return fmt.Sprintf("ghcr.io/graalvm/jdk-community:%d", version)
+		},
+		// graalvm does not publish jre images
+		runtime: func(version int, _ string) string {
+			return fmt.Sprintf("ghcr.io/graalvm/jdk-community:%d", version)
+		},
+	},
+}
+
+// GetJDK returns the distribution selected by the jdk property, eclipse-temurin by default.
+func GetJDK(build container.Build) (JDK, error) {
+	name := build.Custom.String("jdk")
+	if name == "" {
+		name = DEFAULT_JDK
+	}
+	for _, jdk := range JDKs {
+		if jdk.Name == name {
+			return jdk, nil
+		}
+	}
+	return JDK{}, fmt.Errorf("unsupported jdk distribution %q", name)
+}
+
+// Supports reports whether the distribution provides the builder version, e.g. v21.
+func (j JDK) Supports(version string) bool {
+	return slices.ContainsFunc(j.Versions, func(v int) bool {
+		return version == fmt.Sprintf("v%d", v)
+	})
+}
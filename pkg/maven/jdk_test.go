@@ -0,0 +1,69 @@
+package maven
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetJDK(t *testing.T) {
+	build := InitTest(t)
+
+	jdk, err := GetJDK(*build)
+	require.NoError(t, err)
+	assert.Equal(t, "temurin", jdk.Name)
+	assert.Equal(t, "eclipse-temurin:21-jdk-noble", jdk.BaseImage(21, "noble"))
+
+	build.Custom["jdk"] = []string{"unknown"}
+	_, err = GetJDK(*build)
+	assert.Error(t, err)
+	assert.False(t, Matches(*build))
+}
+
+func TestJDKDistributions(t *testing.T) {
+	tests := []struct {
+		jdk            string
+		from           string
+		baseImage      string
+		packageManager string
+		image          string
+	}{
+		{"corretto", "v21", "FROM amazoncorretto:21-al2023-jdk\n", "dnf -y install", "containifyci/maven-3.9.11-amazon-corretto-v21:"},
+		{"zulu", "v11", "FROM azul/zulu-openjdk:11\n", "apt -y install", "containifyci/maven-3.9.11-azul-zulu-v11:"},
+		{"liberica", "v17", "FROM bellsoft/liberica-openjdk-debian:17\n", "apt -y install", "containifyci/maven-3.9.11-bellsoft-liberica-v17:"},
+		{"graalvm", "v25", "FROM ghcr.io/graalvm/jdk-community:25\n", "microdnf -y install", "containifyci/maven-3.9.11-graalvm-community-v25:"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.jdk, func(t *testing.T) {
+			build := InitTest(t)
+			build.Custom["jdk"] = []string{tt.jdk}
+			build.Custom["from"] = []string{tt.from}
+
+			assert.True(t, Matches(*build))
+			dockerFile, err := Dockerfile(*build)
+			require.NoError(t, err)
+			assert.True(t, strings.HasPrefix(string(dockerFile), tt.baseImage))
+			assert.Contains(t, string(dockerFile), tt.packageManager)
+			if strings.HasPrefix(tt.packageManager, "apt") {
+				assert.Contains(t, string(dockerFile), " curl ca-certificates && ")
+			} else {
+				// the curl-minimal of the rpm based images already provides curl
+				assert.Contains(t, string(dockerFile), `if ! command -v curl >/dev/null 2>&1; then packages="$packages curl"; fi`)
+				assert.Contains(t, string(dockerFile), " -y install $packages && ")
+			}
+			assert.True(t, strings.HasPrefix(MavenImage(*build), tt.image))
+		})
+	}
+}
+
+func TestJDKUnsupportedVersion(t *testing.T) {
+	build := InitTest(t)
+	build.Custom["jdk"] = []string{"graalvm"}
+	build.Custom["from"] = []string{"v11"}
+
+	assert.False(t, Matches(*build))
+	_, err := Dockerfile(*build)
+	assert.Error(t, err)
+}
@@ -0,0 +1,362 @@
+package maven
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/containifyci/engine-ci/pkg/build"
+	"github.com/containifyci/engine-ci/pkg/container"
+)
+
+// Linters supported by the maven-lint step.
+const (
+	Checkstyle = "checkstyle"
+	SpotBugs   = "spotbugs"
+	PMD        = "pmd"
+)
+
+// Severities of findings, ordered by rank.
+const (
+	SeverityInfo    = "info"
+	SeverityWarning = "warning"
+	SeverityError   = "error"
+	SeverityNone    = "none"
+)
+
+var severityRank = map[string]int{SeverityInfo: 1, SeverityWarning: 2, SeverityError: 3, SeverityNone: 4}
+
+// LintGoals are the report goals of the linters. The plugins are given
+// without version, so the version and configuration of the project's pom
+// apply when it declares them.
+var LintGoals = map[string]string{
+	Checkstyle: "org.apache.maven.plugins:maven-checkstyle-plugin:checkstyle",
+	SpotBugs:   "com.github.spotbugs:spotbugs-maven-plugin:spotbugs",
+	PMD:        "org.apache.maven.plugins:maven-pmd-plugin:pmd",
+}
+
+// LintReports are the XML reports of the linters below target/.
+var LintReports = map[string]string{
+	Checkstyle: "checkstyle-result.xml",
+	SpotBugs:   "spotbugsXml.xml",
+	PMD:        "pmd.xml",
+}
+
+// Finding is a violation reported by one of the linters.
+type Finding struct {
+	Tool     string
+	Module   string
+	File     string
+	Line     int
+	Rule     string
+	Severity string
+	Message  string
+}
+
+func NewLint() build.BuildStep {
+	return build.Stepper{
+		RunFn: func(build container.Build) (string, error) {
+			container := new(&build)
+			return container.RunLint()
+		},
+		MatchedFn: func(build container.Build) bool {
+			return len(GetLinters(build)) > 0 && Matches(build)
+		},
+		ImagesFn: func(build container.Build) []string {
+			return []string{MavenImage(build)}
+		},
+		Name_:  "maven-lint",
+		Async_: false,
+	}
+}
+
+// GetLinters returns the linters listed in the lint property.
+func GetLinters(build container.Build) []string {
+	var linters []string
+	for _, linter := range customList(&build, "lint") {
+		linter = strings.ToLower(linter)
+		if _, ok := LintGoals[linter]; !ok {
+			slog.Warn("Ignoring unknown linter", "linter", linter)
+			continue
+		}
+		linters = append(linters, linter)
+	}
+	return linters
+}
+
+// GetLintSeverity returns the lint_severity threshold, error by default.
+func GetLintSeverity(build container.Build) (string, error) {
+	severity := strings.ToLower(build.Custom.String("lint_severity"))
+	if severity == "" {
+		return SeverityError, nil
+	}
+	if _, ok := severityRank[severity]; !ok {
+		return "", fmt.Errorf("invalid lint_severity %q", severity)
+	}
+	return severity, nil
+}
+
+// LintScript runs the report goals of linters, compiling first for SpotBugs
+// which analyses the class files.
+func (c *MavenContainer) LintScript(linters []string) string {
+	bs := c.NewBuildScript()
+	bs.Goals = nil
+	for _, linter := range linters {
+		if linter == SpotBugs {
+			bs.Goals = []string{"compile"}
+		}
+	}
+	for _, linter := range linters {
+		bs.Goals = append(bs.Goals, LintGoals[linter])
+	}
+	bs.SkipTests = true
+	// the prefetch does not cover the linter plugins
+	bs.Offline = false
+	return Script(bs)
+}
+
+func (c *MavenContainer) Lint() error {
+	linters := GetLinters(*c.GetBuild())
+	severity, err := GetLintSeverity(*c.GetBuild())
+	if err != nil {
+		slog.Error("Failed to read lint severity", "error", err)
+		os.Exit(1)
+	}
+
+	opts, cleanup := c.BuildOpts()
+	opts.Script = c.LintScript(linters)
+
+	start := time.Now()
+	err = buildingContainer(c, opts)
+	cleanup()
+	if err != nil {
+		slog.Error("Failed to run linters", "error", err)
+		os.Exit(1)
+	}
+
+	findings, err := ReadFindings(c.Folder, start, linters...)
+	if err != nil {
+		slog.Error("Failed to read lint reports", "error", err)
+		os.Exit(1)
+	}
+	if err := CheckFindings(os.Stdout, findings, severity); err != nil {
+		slog.Error("Lint failed", "error", err)
+		os.Exit(1)
+	}
+	return nil
+}
+
+func (c *MavenContainer) RunLint() (string, error) {
+	err := c.BuildMavenImage()
+	if err != nil {
+		slog.Error("Failed to build maven image", "error", err)
+		return "", err
+	}
+
+	err = c.Lint()
+	if err != nil {
+		return "", err
+	}
+	return c.ID, nil
+}
+
+// ReadFindings collects the findings of the linters from the reports the
+// modules below folder wrote since since.
+func ReadFindings(folder string, since time.Time, linters ...string) ([]Finding, error) {
+	var findings []Finding
+	for _, linter := range linters {
+		files, err := findTargetFiles(folder, since, LintReports[linter])
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			data, err := os.ReadFile(file.Path)
+			if err != nil {
+				return nil, err
+			}
+			parsed, err := parseFindings(linter, data)
+			if err != nil {
+				slog.Warn("Skipping unreadable lint report", "file", file.Path, "error", err)
+				continue
+			}
+			for _, f := range parsed {
+				f.Tool, f.Module = linter, file.Module
+				f.File = sourcePath(file.Module, f.File)
+				findings = append(findings, f)
+			}
+		}
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].File != findings[j].File {
+			return findings[i].File < findings[j].File
+		}
+		return findings[i].Line < findings[j].Line
+	})
+	return findings, nil
+}
+
+// sourcePath makes the paths of the reports relative to the project folder,
+// they are absolute paths inside the build container.
+func sourcePath(module, file string) string {
+	if rel, ok := strings.CutPrefix(file, "/src/"); ok {
+		return rel
+	}
+	if path.IsAbs(file) {
+		return file
+	}
+	// SpotBugs reports source paths relative to the source folder
+	return path.Join(module, "src/main/java", file)
+}
+
+func parseFindings(linter string, data []byte) ([]Finding, error) {
+	switch linter {
+	case Checkstyle:
+		return parseCheckstyle(data)
+	case SpotBugs:
+		return parseSpotBugs(data)
+	case PMD:
+		return parsePMD(data)
+	}
+	return nil, fmt.Errorf("unknown linter %s", linter)
+}
+
+func parseCheckstyle(data []byte) ([]Finding, error) {
+	var report struct {
+		Files []struct {
+			Name   string `xml:"name,attr"`
+			Errors []struct {
+				Line     int    `xml:"line,attr"`
+				Severity string `xml:"severity,attr"`
+				Message  string `xml:"message,attr"`
+				Source   string `xml:"source,attr"`
+			} `xml:"error"`
+		} `xml:"file"`
+	}
+	if err := xml.Unmarshal(data, &report); err != nil {
+		return nil, err
+	}
+	var findings []Finding
+	for _, file := range report.Files {
+		for _, e := range file.Errors {
+			if e.Severity == "ignore" {
+				continue
+			}
+			rule := e.Source[strings.LastIndex(e.Source, ".")+1:]
+			findings = append(findings, Finding{
+				File:     file.Name,
+				Line:     e.Line,
+				Rule:     strings.TrimSuffix(rule, "Check"),
+				Severity: e.Severity,
+				Message:  e.Message,
+			})
+		}
+	}
+	return findings, nil
+}
+
+func parsePMD(data []byte) ([]Finding, error) {
+	var report struct {
+		Files []struct {
+			Name       string `xml:"name,attr"`
+			Violations []struct {
+				Line     int    `xml:"beginline,attr"`
+				Rule     string `xml:"rule,attr"`
+				Priority int    `xml:"priority,attr"`
+				Message  string `xml:",chardata"`
+			} `xml:"violation"`
+		} `xml:"file"`
+	}
+	if err := xml.Unmarshal(data, &report); err != nil {
+		return nil, err
+	}
+	var findings []Finding
+	for _, file := range report.Files {
+		for _, v := range file.Violations {
+			// PMD priorities range from 1 (high) to 5 (low)
+			severity := SeverityInfo
+			switch {
+			case v.Priority <= 2:
+				severity = SeverityError
+			case v.Priority == 3:
+				severity = SeverityWarning
+			}
+			findings = append(findings, Finding{
+				File:     file.Name,
+				Line:     v.Line,
+				Rule:     v.Rule,
+				Severity: severity,
+				Message:  strings.TrimSpace(v.Message),
+			})
+		}
+	}
+	return findings, nil
+}
+
+func parseSpotBugs(data []byte) ([]Finding, error) {
+	var report struct {
+		Bugs []struct {
+			Type        string `xml:"type,attr"`
+			Priority    int    `xml:"priority,attr"`
+			LongMessage string `xml:"LongMessage"`
+			SourceLines []struct {
+				SourcePath string `xml:"sourcepath,attr"`
+				Start      string `xml:"start,attr"`
+			} `xml:"SourceLine"`
+		} `xml:"BugInstance"`
+	}
+	if err := xml.Unmarshal(data, &report); err != nil {
+		return nil, err
+	}
+	var findings []Finding
+	for _, bug := range report.Bugs {
+		// SpotBugs priorities range from 1 (high) to 3 (low)
+		severity := SeverityInfo
+		switch bug.Priority {
+		case 1:
+			severity = SeverityError
+		case 2:
+			severity = SeverityWarning
+		}
+		finding := Finding{Rule: bug.Type, Severity: severity, Message: bug.LongMessage}
+		if len(bug.SourceLines) > 0 {
+			finding.File = bug.SourceLines[0].SourcePath
+			finding.Line, _ = strconv.Atoi(bug.SourceLines[0].Start)
+		}
+		findings = append(findings, finding)
+	}
+	return findings, nil
+}
+
+// CheckFindings prints the findings and fails when one of them is at least
+// of severity.
+func CheckFindings(w io.Writer, findings []Finding, severity string) error {
+	if len(findings) == 0 {
+		slog.Info("No lint findings")
+		return nil
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "FILE\tLINE\tTOOL\tSEVERITY\tRULE\tMESSAGE")
+	failed := 0
+	for _, f := range findings {
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%s\t%s\n", f.File, f.Line, f.Tool, f.Severity, f.Rule, f.Message)
+		if severityRank[f.Severity] >= severityRank[severity] {
+			failed++
+		}
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d findings are of severity %s or higher", failed, len(findings), severity)
+	}
+	slog.Info("Lint findings below threshold", "findings", len(findings), "severity", severity)
+	return nil
+}
@@ -0,0 +1,115 @@
+package maven
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/containifyci/engine-ci/pkg/container"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const checkstyleReport = `<?xml version="1.0" encoding="UTF-8"?>
+<checkstyle version="10.12.4">
+<file name="/src/core/src/main/java/com/example/Service.java">
+<error line="12" column="5" severity="warning" message="Missing a Javadoc comment." source="com.puppycrawl.tools.checkstyle.checks.javadoc.MissingJavadocMethodCheck"/>
+<error line="3" severity="ignore" message="ignored" source="com.puppycrawl.tools.checkstyle.checks.imports.AvoidStarImportCheck"/>
+</file>
+</checkstyle>`
+
+const pmdReport = `<?xml version="1.0" encoding="UTF-8"?>
+<pmd xmlns="http://pmd.sourceforge.net/report/2.0.0" version="7.0.0">
+<file name="/src/core/src/main/java/com/example/Service.java">
+<violation beginline="20" endline="20" rule="EmptyCatchBlock" ruleset="Error Prone" priority="3">
+Avoid empty catch blocks
+</violation>
+<violation beginline="30" endline="31" rule="UnusedPrivateField" ruleset="Best Practices" priority="1">
+Avoid unused private fields such as 'name'.
+</violation>
+</file>
+</pmd>`
+
+const spotbugsReport = `<?xml version="1.0" encoding="UTF-8"?>
+<BugCollection version="4.8.3">
+<BugInstance type="NP_NULL_ON_SOME_PATH" priority="2" rank="10" category="CORRECTNESS">
+<ShortMessage>Possible null pointer dereference</ShortMessage>
+<LongMessage>Possible null pointer dereference of value in com.example.Service.run()</LongMessage>
+<Class classname="com.example.Service"><SourceLine classname="com.example.Service" sourcepath="com/example/Service.java" start="1" end="40"/></Class>
+<SourceLine classname="com.example.Service" start="25" end="25" sourcepath="com/example/Service.java" primary="true"/>
+</BugInstance>
+<BugInstance type="DLS_DEAD_LOCAL_STORE" priority="3" rank="18" category="STYLE">
+<LongMessage>Dead store to x in com.example.Service.run()</LongMessage>
+<SourceLine classname="com.example.Service" start="26" end="26" sourcepath="com/example/Service.java"/>
+</BugInstance>
+</BugCollection>`
+
+func TestGetLinters(t *testing.T) {
+	build := container.Build{Custom: container.Custom{"lint": {"Checkstyle, pmd", "sonar"}}}
+	assert.Equal(t, []string{Checkstyle, PMD}, GetLinters(build))
+	assert.Empty(t, GetLinters(container.Build{}))
+
+	severity, err := GetLintSeverity(container.Build{})
+	require.NoError(t, err)
+	assert.Equal(t, SeverityError, severity)
+	severity, err = GetLintSeverity(container.Build{Custom: container.Custom{"lint_severity": {"Warning"}}})
+	require.NoError(t, err)
+	assert.Equal(t, SeverityWarning, severity)
+	_, err = GetLintSeverity(container.Build{Custom: container.Custom{"lint_severity": {"fatal"}}})
+	assert.Error(t, err)
+}
+
+func TestLintScript(t *testing.T) {
+	arg := InitTest(t)
+	arg.Custom["goals"] = []string{"verify"}
+	arg.Custom["profiles"] = []string{"ci"}
+
+	mc := new(arg)
+	assert.Equal(t, "#!/bin/sh\nset -xe\ncd .\nmvn --batch-mode --threads 2 org.apache.maven.plugins:maven-checkstyle-plugin:checkstyle -Pci -DskipTests\n",
+		mc.LintScript([]string{Checkstyle}))
+	assert.Equal(t, "#!/bin/sh\nset -xe\ncd .\nmvn --batch-mode --threads 2 compile org.apache.maven.plugins:maven-pmd-plugin:pmd com.github.spotbugs:spotbugs-maven-plugin:spotbugs -Pci -DskipTests\n",
+		mc.LintScript([]string{PMD, SpotBugs}))
+}
+
+func TestReadFindings(t *testing.T) {
+	folder := t.TempDir()
+	writeReport(t, filepath.Join(folder, "core/target"), "checkstyle-result.xml", checkstyleReport)
+	writeReport(t, filepath.Join(folder, "core/target"), "pmd.xml", pmdReport)
+	writeReport(t, filepath.Join(folder, "core/target"), "spotbugsXml.xml", spotbugsReport)
+	writeReport(t, filepath.Join(folder, "web/target"), "pmd.xml", `<pmd`)
+
+	findings, err := ReadFindings(folder, time.Time{}, Checkstyle, SpotBugs, PMD)
+	require.NoError(t, err)
+	file := "core/src/main/java/com/example/Service.java"
+	assert.Equal(t, []Finding{
+		{Tool: Checkstyle, Module: "core", File: file, Line: 12, Rule: "MissingJavadocMethod", Severity: SeverityWarning, Message: "Missing a Javadoc comment."},
+		{Tool: PMD, Module: "core", File: file, Line: 20, Rule: "EmptyCatchBlock", Severity: SeverityWarning, Message: "Avoid empty catch blocks"},
+		{Tool: SpotBugs, Module: "core", File: file, Line: 25, Rule: "NP_NULL_ON_SOME_PATH", Severity: SeverityWarning, Message: "Possible null pointer dereference of value in com.example.Service.run()"},
+		{Tool: SpotBugs, Module: "core", File: file, Line: 26, Rule: "DLS_DEAD_LOCAL_STORE", Severity: SeverityInfo, Message: "Dead store to x in com.example.Service.run()"},
+		{Tool: PMD, Module: "core", File: file, Line: 30, Rule: "UnusedPrivateField", Severity: SeverityError, Message: "Avoid unused private fields such as 'name'."},
+	}, findings)
+
+	findings, err = ReadFindings(folder, time.Time{}, Checkstyle)
+	require.NoError(t, err)
+	assert.Len(t, findings, 1)
+}
+
+func TestCheckFindings(t *testing.T) {
+	findings := []Finding{
+		{Tool: Checkstyle, File: "Service.java", Line: 12, Rule: "MissingJavadocMethod", Severity: SeverityWarning, Message: "Missing a Javadoc comment."},
+		{Tool: PMD, File: "Service.java", Line: 30, Rule: "UnusedPrivateField", Severity: SeverityError, Message: "Avoid unused private fields"},
+	}
+	var out bytes.Buffer
+	assert.NoError(t, CheckFindings(&out, nil, SeverityInfo))
+	assert.Empty(t, out.String())
+
+	assert.EqualError(t, CheckFindings(&out, findings, SeverityError), "1 of 2 findings are of severity error or higher")
+	assert.Equal(t, `FILE          LINE  TOOL        SEVERITY  RULE                  MESSAGE
+Service.java  12    checkstyle  warning   MissingJavadocMethod  Missing a Javadoc comment.
+Service.java  30    pmd         error     UnusedPrivateField    Avoid unused private fields
+`, out.String())
+
+	assert.EqualError(t, CheckFindings(&out, findings, SeverityWarning), "2 of 2 findings are of severity warning or higher")
+	assert.NoError(t, CheckFindings(&out, findings, SeverityNone))
+}
@@ -9,6 +9,11 @@ import (
 	"os"
 	"os/user"
 	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
 
 	"github.com/containifyci/engine-ci/pkg/build"
 	"github.com/containifyci/engine-ci/pkg/container"
@@ -18,6 +23,7 @@ import (
 	"github.com/containifyci/engine-ci/pkg/filesystem"
 	"github.com/containifyci/engine-ci/pkg/network"
 	u "github.com/containifyci/engine-ci/pkg/utils"
+	"github.com/containifyci/engine-java/pkg/buildtool"
 )
 
 const (
@@ -37,6 +43,8 @@ type MavenContainer struct {
 	ImageTag  string
 	Platform  types.Platform
 	ProdImage string
+	// ProbeImage runs the smoke test probe, the maven builder image by default.
+	ProbeImage string
 
 	Version string
 	*container.Container
@@ -56,11 +64,21 @@ func New() build.BuildStep {
 }
 
 func Matches(build container.Build) bool {
-	if build.BuildType != container.Maven {
+	if build.BuildType != container.Maven || buildtool.IsGradle(build) {
 		return false
 	}
-	version := GetVersion(build)
-	return version == "v17" || version == "v21"
+	jdk, err := GetJDK(build)
+	if err != nil {
+		slog.Warn("Not building with maven", "error", err)
+		return false
+	}
+	return jdk.Supports(GetVersion(build))
+}
+
+// NewContainer returns the maven container of build, the gradle prod step
+// ships its artifacts with it.
+func NewContainer(build *container.Build) *MavenContainer {
+	return new(build)
 }
 
 func new(build *container.Build) *MavenContainer {
@@ -72,7 +90,7 @@ func new(build *container.Build) *MavenContainer {
 		File:      u.SrcFile(build.File),
 		ImageTag:  build.ImageTag,
 		Platform:  build.Platform,
-		ProdImage: ProdImage(*build),
+		ProdImage: prodImage(*build),
 		Version:   GetVersion(*build),
 	}
 }
@@ -85,51 +103,158 @@ func (c *MavenContainer) Name() string {
 	return "maven"
 }
 
+// builderVersions caches the builder version of a build by versionKey, it is
+// asked for by every step and image name and the pom.xml is read once.
+var builderVersions sync.Map
+
+// GetVersion returns the builder version of build, e.g. v17: the from
+// property, the one detected from the pom.xml or else DEFAULT_MAVEN_VERSION.
 func GetVersion(build container.Build) string {
+	key := versionKey(build)
+	if version, ok := builderVersions.Load(key); ok {
+		return version.(string)
+	}
+	version := getVersion(build)
+	builderVersions.Store(key, version)
+	return version
+}
+
+// versionKey identifies what the builder version depends on, the from and jdk
+// properties and the pom.xml of the project.
+func versionKey(build container.Build) string {
+	folder, _ := filepath.Abs(build.Folder)
+	pom, _ := os.ReadFile(filepath.Join(folder, PomFile))
+	sum := sha256.Sum256(pom)
+	return strings.Join([]string{build.Custom.String("from"), build.Custom.String("jdk"), folder, hex.EncodeToString(sum[:])}, "\x00")
+}
+
+func getVersion(build container.Build) string {
 	var from string
 	if v, ok := build.Custom["from"]; ok {
 		slog.Info("Using custom build", "from", v[0])
 		from = v[0]
 	}
+	if from == "" {
+		if jdk, err := GetJDK(build); err == nil {
+			from = DetectVersion(build.Folder, jdk.Versions)
+		}
+	}
 	if from == "" {
 		from = DEFAULT_MAVEN_VERSION
 	}
 	return from
 }
 
+// DetectVersion picks the builder for the Java version declared in the pom.xml
+// of folder. Projects targeting a release without a builder are built on the
+// oldest newer JDK of versions and projects newer than every builder on the
+// newest one. It returns an empty string when the pom does not declare a version.
+func DetectVersion(folder string, versions []int) string {
+	pom, err := ReadPom(filepath.Join(folder, PomFile))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			slog.Warn("Failed to read pom.xml", "error", err)
+		}
+		return ""
+	}
+	java, source := pom.JavaVersion()
+	if java == 0 {
+		return ""
+	}
+	for _, v := range versions {
+		if v >= java {
+			version := fmt.Sprintf("v%d", v)
+			slog.Info("Detected java version from pom.xml", "java", java, "source", source, "from", version)
+			return version
+		}
+	}
+	version := fmt.Sprintf("v%d", slices.Max(versions))
+	slog.Warn("No maven builder for the java version of pom.xml, using the newest one", "java", java, "source", source, "from", version)
+	return version
+}
+
+// CacheFolder returns the host folder mounted at CacheLocation, MAVEN_HOME,
+// CONTAINIFYCI_CACHE or ~/.m2.
 func CacheFolder() string {
 	mvnHome := u.GetEnvs([]string{"MAVEN_HOME", "CONTAINIFYCI_CACHE"}, "build")
 	if mvnHome == "" {
-		usr, err := user.Current()
-		if err != nil {
-			slog.Error("Failed to get current user", "error", err)
-			os.Exit(1)
-		}
-		mvnHome = fmt.Sprintf("%s%s%s", usr.HomeDir, string(os.PathSeparator), ".m2")
+		mvnHome = HomeCacheFolder(".m2")
 		slog.Info("MAVEN_HOME not set, using default", "mavenHome", mvnHome)
-		err = filesystem.DirectoryExists(mvnHome)
-		if err != nil {
-			slog.Error("Failed to create cache folder", "error", err)
-			os.Exit(1)
-		}
 	}
 	return mvnHome
 }
 
+// HomeCacheFolder returns the folder name in the home of the current user,
+// created when missing. The build tools cache there unless told otherwise.
+func HomeCacheFolder(name string) string {
+	usr, err := user.Current()
+	if err != nil {
+		slog.Error("Failed to get current user", "error", err)
+		os.Exit(1)
+	}
+	return EnsureCacheFolder(filepath.Join(usr.HomeDir, name))
+}
+
+// EnsureCacheFolder creates the cache folder when missing and returns it.
+func EnsureCacheFolder(folder string) string {
+	if err := filesystem.DirectoryExists(folder); err != nil {
+		slog.Error("Failed to create cache folder", "error", err)
+		os.Exit(1)
+	}
+	return folder
+}
+
 func (c *MavenContainer) Pull() error {
 	return c.Container.Pull(c.ProdImage)
 }
 
 func Images(build container.Build) []string {
-	return []string{MavenImage(build), ProdImage(build)}
+	return append([]string{MavenImage(build)}, prodImages(build)...)
 }
 
-func ProdImage(build container.Build) string {
+// ProdImage returns the base image of the prod image, image when set, the JRE
+// of the builder JDK for jars and the app server for wars and ears.
+func ProdImage(build container.Build) (string, error) {
 	prodImage := build.Custom.String("image")
-	if prodImage == "" {
-		prodImage = PRODIMAGE
+	if prodImage != "" {
+		return prodImage, nil
+	}
+	artifact, err := ArtifactType(build)
+	if err != nil {
+		return "", err
 	}
-	return prodImage
+	if artifact == ArtifactJar {
+		args, err := NewDockerfileArgs(build)
+		if err != nil {
+			return "", fmt.Errorf("failed to select jre image: %w", err)
+		}
+		jdk, _ := GetJDK(build)
+		return jdk.RuntimeImage(args.JDK, args.Distro), nil
+	}
+	server, err := GetAppServer(build)
+	if err != nil {
+		return "", fmt.Errorf("failed to select app server: %w", err)
+	}
+	return server.Image, nil
+}
+
+// prodImage returns the prod base image of the container. It is empty when
+// the build ships no artifact, the prod step reports why when it runs.
+func prodImage(build container.Build) string {
+	image, err := ProdImage(build)
+	if err != nil {
+		slog.Debug("No prod image", "error", err)
+	}
+	return image
+}
+
+// prodImages lists the prod base image to pull, none when the build ships no
+// artifact.
+func prodImages(build container.Build) []string {
+	if image := prodImage(build); image != "" {
+		return []string{image}
+	}
+	return nil
 }
 
 // TODO: provide a shorter checksum
@@ -139,24 +264,32 @@ func ComputeChecksum(data []byte) string {
 }
 
 func MavenImage(build container.Build) string {
-	fileName := fmt.Sprintf("Dockerfile.maven_%s-jdk-jammy", GetVersion(build))
-	dockerFile, err := f.ReadFile(fileName)
+	dockerFile, err := Dockerfile(build)
 	if err != nil {
-		slog.Error("Failed to read Dockerfile.maven", "error", err)
+		slog.Error("Failed to render Dockerfile.maven", "error", err)
+		os.Exit(1)
+	}
+	jdk, err := GetJDK(build)
+	if err != nil {
+		slog.Error("Failed to select jdk distribution", "error", err)
+		os.Exit(1)
+	}
+	args, err := NewDockerfileArgs(build)
+	if err != nil {
+		slog.Error("Failed to render Dockerfile.maven", "error", err)
 		os.Exit(1)
 	}
 	tag := ComputeChecksum(dockerFile)
-	image := fmt.Sprintf("maven-3-eclipse-temurin-%s-alpine", GetVersion(build))
+	image := fmt.Sprintf("maven-%s-%s-%s", args.MavenVersion, jdk.Vendor, GetVersion(build))
 	return utils.ImageURI(build.ContainifyRegistry, image, tag)
 }
 
 func (c *MavenContainer) BuildMavenImage() error {
 	image := MavenImage(*c.GetBuild())
-	fileName := fmt.Sprintf("Dockerfile.maven_%s-jdk-jammy", c.Version)
-	slog.Debug("Building maven image", "image", image, "fileName", fileName)
-	dockerFile, err := f.ReadFile(fileName)
+	slog.Debug("Building maven image", "image", image, "version", c.Version)
+	dockerFile, err := Dockerfile(*c.GetBuild())
 	if err != nil {
-		slog.Error("Failed to read Dockerfile.maven", "error", err)
+		slog.Error("Failed to render Dockerfile.maven", "error", err)
 		os.Exit(1)
 	}
 
@@ -175,27 +308,34 @@ func (c *MavenContainer) Address() *network.Address {
 	return &network.Address{Host: "localhost"}
 }
 
-func (c *MavenContainer) Build() error {
-	imageTag := MavenImage(*c.GetBuild())
+// buildingContainer runs opts in a build container, the tests replace it to
+// inspect the containers of the steps.
+var buildingContainer = func(c *MavenContainer, opts types.ContainerConfig) error {
+	return c.BuildingContainer(opts)
+}
 
-	ssh, err := network.SSHForward(*c.GetBuild())
+// ContainerOpts returns the configuration shared by the containers building a
+// Java project in the builder image: env, the project mounted at /src, the
+// cacheFolder of the host at cacheLocation, the limits of res, the forwarded
+// SSH agent and the testcontainers settings of the runtime.
+func ContainerOpts(build *container.Build, image string, env []string, cacheFolder, cacheLocation string, res Resources) types.ContainerConfig {
+	ssh, err := network.SSHForward(*build)
 	if err != nil {
 		slog.Error("Failed to forward SSH", "error", err)
 		os.Exit(1)
 	}
 
 	opts := types.ContainerConfig{}
-	opts.Image = imageTag
-	opts.Env = append(opts.Env, []string{
-		"MAVEN_OPTS=-Xms512m -Xmx512m -XX:MaxDirectMemorySize=512m",
-		fmt.Sprintf("CONTAINIFYCI_HOST=%s", getContainifyHost(c.GetBuild())),
-	}...)
+	opts.Image = image
+	opts.Env = append(opts.Env, env...)
+	opts.Env = append(opts.Env, fmt.Sprintf("CONTAINIFYCI_HOST=%s", ContainifyHost(build)))
 
 	// On MacOS, we need to set a special docker host so that the testcontainers can access the host
-	if c.Platform.Host.OS == "darwin" {
+	if build.Platform.Host.OS == "darwin" {
+		address := &network.Address{Host: "localhost"}
 		opts.Env = append(opts.Env, []string{
-			fmt.Sprintf("TC_HOST=%s", c.Address().ForContainerDefault(c.GetBuild())),
-			fmt.Sprintf("TESTCONTAINERS_HOST_OVERRIDE=%s", c.Address().ForContainerDefault(c.GetBuild())),
+			fmt.Sprintf("TC_HOST=%s", address.ForContainerDefault(build)),
+			fmt.Sprintf("TESTCONTAINERS_HOST_OVERRIDE=%s", address.ForContainerDefault(build)),
 		}...)
 	}
 
@@ -211,17 +351,17 @@ func (c *MavenContainer) Build() error {
 		},
 		{
 			Type:   "bind",
-			Source: CacheFolder(),
-			Target: CacheLocation,
+			Source: cacheFolder,
+			Target: cacheLocation,
 		},
 	}
-	opts.Memory = int64(4073741824)
-	opts.CPU = uint64(2048)
+	opts.Memory = res.Memory
+	opts.CPU = res.CPU
 
 	opts = ssh.Apply(&opts)
-	opts = utils.ApplySocket(c.GetBuild().Runtime, &opts)
+	opts = utils.ApplySocket(build.Runtime, &opts)
 
-	if c.GetBuild().Runtime == utils.Podman {
+	if build.Runtime == utils.Podman {
 		//https://stackoverflow.com/questions/71549856/testcontainers-with-podman-in-java-tests
 		opts.Env = append(opts.Env, []string{
 			"DOCKER_HOST=unix://var/run/podman.sock",
@@ -237,28 +377,337 @@ func (c *MavenContainer) Build() error {
 		)
 	}
 
-	opts.Script = c.BuildScript()
+	return opts
+}
+
+// BuildOpts returns the configuration of a container running mvn in the
+// builder image with the project and the repository cache mounted. With
+// cache_read_only a throwaway copy of the cache is mounted instead, the
+// returned func removes it once the container is done.
+func (c *MavenContainer) BuildOpts() (types.ContainerConfig, func()) {
+	res := c.Resources()
+	env := []string{fmt.Sprintf("MAVEN_OPTS=%s", res.MavenOpts)}
+	if settings := c.Settings(); settings != nil {
+		credentials, err := settings.Env()
+		if err != nil {
+			slog.Error("Failed to resolve maven credentials", "error", err)
+			os.Exit(1)
+		}
+		env = append(env, credentials...)
+	}
+
+	cacheFolder := CacheFolder()
+	cleanup := func() {}
+	if scope := c.CacheScope(); scope.ReadOnly {
+		snapshot, err := scope.Snapshot(cacheFolder)
+		if err != nil {
+			slog.Error("Failed to copy the maven cache for the read-only build", "error", err)
+			os.Exit(1)
+		}
+		cacheFolder = snapshot
+		cleanup = func() {
+			if err := os.RemoveAll(snapshot); err != nil {
+				slog.Warn("Failed to remove the copy of the maven cache", "folder", snapshot, "error", err)
+			}
+		}
+	}
+
+	return ContainerOpts(c.GetBuild(), MavenImage(*c.GetBuild()), env, cacheFolder, CacheLocation, res), cleanup
+}
+
+func (c *MavenContainer) Build() error {
+	gate, err := GetCoverageGate(*c.GetBuild())
+	if err != nil {
+		slog.Error("Failed to read coverage thresholds", "error", err)
+		os.Exit(1)
+	}
+
+	bs := c.NewBuildScript()
+	if bs.Reactor != nil && bs.Reactor.Unchanged {
+		slog.Info("No reactor module changed, skipping the maven build", "changed_since", c.GetBuild().Custom.String("changed_since"))
+		return nil
+	}
+	if bs.Reactor != nil {
+		slog.Info("Building reactor modules", "modules", bs.Reactor.Modules)
+	}
+
+	bs.KeepLog = true
 
-	err = c.BuildingContainer(opts)
+	daemon, err := GetDaemon(*c.GetBuild())
+	if err != nil {
+		slog.Error("Failed to configure mvnd", "error", err)
+		os.Exit(1)
+	}
+	bs.Daemon = daemon != nil
+	if bs.Daemon {
+		bs.DaemonJVMArgs = c.Resources().MavenOpts
+	}
+	if bs.Offline && bs.Daemon {
+		slog.Error("An offline build needs a container of its own without network, it cannot run in the mvnd builder container")
+		os.Exit(1)
+	}
+	if bs.CacheScope.ReadOnly && bs.Daemon {
+		slog.Error("A read-only cache is mounted as a copy discarded after each build, it cannot be mounted into the long-lived mvnd builder container")
+		os.Exit(1)
+	}
+
+	opts, cleanup := c.BuildOpts()
+	opts.Script = Script(bs)
+
+	// a stale log of a previous run must not be taken for this one
+	_ = os.Remove(filepath.Join(c.Folder, BuildLog))
+
+	cache := bs.BuildCache
+	var written []string
+	if cache != nil {
+		written, err = cache.Provision(c.Folder)
+		if err != nil {
+			slog.Error("Failed to set up the maven build cache", "error", err)
+			os.Exit(1)
+		}
+		for _, file := range written {
+			slog.Info("Provisioned maven build cache extension for this build", "file", file)
+		}
+	}
+
+	start := time.Now()
+	switch {
+	case daemon != nil:
+		err = daemon.Build(opts)
+	case bs.Offline:
+		err = c.OfflineBuild(opts, PrefetchScript(bs))
+	default:
+		err = buildingContainer(c, opts)
+	}
+	if cache != nil {
+		if cleanupErr := cache.Cleanup(c.Folder, written); cleanupErr != nil {
+			slog.Warn("Failed to remove the provisioned build cache extension", "error", cleanupErr)
+		}
+	}
+	cleanup()
+	if reportErr := ReportModuleTimings(os.Stdout, c.Folder); reportErr != nil {
+		slog.Warn("Failed to read reactor summary", "error", reportErr)
+	}
+	if cache != nil {
+		if reportErr := ReportBuildCache(c.Folder); reportErr != nil {
+			slog.Warn("Failed to read build cache results", "error", reportErr)
+		}
+	}
+	// report the tests before failing so the failing tests are listed
+	if _, reportErr := ReportTests(c.Folder, start, c.GetBuild().Custom.String("junit_report")); reportErr != nil {
+		slog.Warn("Failed to read test reports", "error", reportErr)
+	}
 	if err != nil {
 		slog.Error("Failed to build container", "error", err)
 		os.Exit(1)
 	}
 
+	if !c.GetBuild().Custom.Bool("skip_tests", false) {
+		if err := ReportCoverage(os.Stdout, c.Folder, start, gate); err != nil {
+			slog.Error("Coverage check failed", "error", err)
+			os.Exit(1)
+		}
+	}
+
 	return err
 }
 
+// OfflineBuild runs the prefetch script in a build container with network
+// and then the build of opts in a second one without. That container is
+// created by engine-ci, detached from all networks through the runtime and
+// only then started. The build fails when the network cannot be taken away.
+func (c *MavenContainer) OfflineBuild(opts types.ContainerConfig, prefetch string) error {
+	prefetchOpts := opts
+	prefetchOpts.Script = prefetch
+	slog.Info("Prefetching dependencies and plugins for the offline build")
+	err := buildingContainer(c, prefetchOpts)
+	if err != nil {
+		return fmt.Errorf("prefetch of the offline build failed: %w", err)
+	}
+
+	cli, err := runtimeClient(c.GetBuild().Runtime)
+	if err != nil {
+		return fmt.Errorf("failed to connect to the container runtime: %w", err)
+	}
+	defer cli.Close()
+
+	offline := container.New(*c.GetBuild())
+	opts.Cmd = []string{"sh", "-c", opts.Script}
+	opts.Script = ""
+	err = offline.Create(opts)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := offline.Stop(); err != nil {
+			slog.Warn("Failed to stop offline build container", "error", err)
+		}
+	}()
+	err = DisconnectNetworks(cli, offline.ID)
+	if err != nil {
+		return fmt.Errorf("cannot run the build offline, the container runtime did not disable its network: %w", err)
+	}
+
+	slog.Info("Building offline without network")
+	err = offline.Start()
+	if err != nil {
+		return err
+	}
+	return offline.Wait()
+}
+
+// ContainifyHost returns the CONTAINIFYCI_HOST of the build.
 // TODO should be moved to the engine-ci itself.
-func getContainifyHost(build *container.Build) string {
+func ContainifyHost(build *container.Build) string {
 	if v, ok := build.Custom["CONTAINIFYCI_HOST"]; ok {
 		return v[0]
 	}
 	return ""
 }
 
+// customList flattens the values of a Custom property, splitting each entry on
+// commas and whitespace so both NewList("clean", "verify") and "clean verify" work.
+func customList(build *container.Build, key string) []string {
+	var list []string
+	for _, v := range build.Custom[key] {
+		list = append(list, strings.FieldsFunc(v, func(r rune) bool {
+			return r == ',' || unicode.IsSpace(r)
+		})...)
+	}
+	return list
+}
+
 func (c *MavenContainer) BuildScript() string {
 	// Create a temporary script in-memory
-	return Script(NewBuildScript(c.Verbose, c.Folder, getContainifyHost(c.GetBuild())))
+	return Script(c.NewBuildScript())
+}
+
+// NewBuildScript configures the mvn invocation from the Custom properties.
+func (c *MavenContainer) NewBuildScript() *BuildScript {
+	build := c.GetBuild()
+	bs := NewBuildScript(c.Verbose, c.Folder, ContainifyHost(build))
+	if goals := customList(build, "goals"); len(goals) > 0 {
+		bs.Goals = goals
+	}
+	bs.Profiles = customList(build, "profiles")
+	bs.Properties = build.Custom["maven_properties"]
+	bs.Args = build.Custom["maven_args"]
+	bs.SkipTests = build.Custom.Bool("skip_tests", false)
+	if build.Custom.Bool("maven_wrapper", true) {
+		wrapper, err := DetectWrapper(c.Folder)
+		if err != nil {
+			slog.Error("Failed to read maven wrapper", "error", err)
+			os.Exit(1)
+		}
+		if wrapper != nil {
+			slog.Info("Using maven wrapper", "distributionUrl", wrapper.DistributionURL, "verified", wrapper.DistributionSha256Sum != "")
+			// an invalid mirror is rejected by c.Settings below
+			wrapper.Mirror, _ = GetMirrorURL(*build)
+		}
+		bs.Wrapper = wrapper
+	}
+	bs.Settings = c.Settings()
+	if bs.Settings != nil {
+		user := filepath.Join(CacheFolder(), "settings.xml")
+		if _, err := os.Stat(user); err == nil {
+			slog.Info("Merging the generated settings with the user settings, the user settings win on clashing ids", "file", user)
+		}
+	}
+	bs.Reactor = c.Reactor()
+	bs.Offline = build.Custom.Bool("offline", false)
+	bs.PrefetchGoals = customList(build, "prefetch_goals")
+	bs.Threads = c.Resources().Threads
+	scope := c.CacheScope()
+	bs.CacheScope = scope
+	bs.Properties = append(bs.Properties[:len(bs.Properties):len(bs.Properties)], scope.Properties()...)
+	if version := c.MavenVersion(bs.Wrapper); scope.Chained() && !SupportsChaining(version) {
+		slog.Warn("Maven before 3.9 ignores maven.repo.local.tail, the build downloads what the cache scope would read from the other repositories", "maven_version", version, "cache_scope", scope.Mode, "cache_read_only", scope.ReadOnly)
+	}
+	bs.BuildCache = c.BuildCache()
+	if bs.BuildCache != nil {
+		bs.Properties = append(bs.Properties, bs.BuildCache.Properties(scope.ReadOnly)...)
+	}
+	return bs
+}
+
+// MavenVersion returns the Maven version running the build, the one of the
+// wrapper distribution or else of the builder image. mvnd ships its own Maven
+// 3.9 and is reported with an empty version.
+func (c *MavenContainer) MavenVersion(wrapper *Wrapper) string {
+	build := c.GetBuild()
+	switch {
+	case build.Custom.Bool("mvnd", false):
+		return ""
+	case wrapper != nil:
+		return wrapper.MavenVersion()
+	case build.Custom.String("maven_version") != "":
+		return build.Custom.String("maven_version")
+	default:
+		return DEFAULT_MAVEN_DIST_VERSION
+	}
+}
+
+// CacheScope returns the local repository selection of the build.
+func (c *MavenContainer) CacheScope() *CacheScope {
+	build := *c.GetBuild()
+	cacheFolder := ""
+	// the shared scope leaves the cache folder alone
+	if mode := strings.ToLower(build.Custom.String("cache_scope")); mode != "" && mode != CacheShared {
+		cacheFolder = CacheFolder()
+	}
+	scope, err := GetCacheScope(build, cacheFolder)
+	if err != nil {
+		slog.Error("Failed to select the maven cache scope", "error", err)
+		os.Exit(1)
+	}
+	return scope
+}
+
+// BuildCache returns the build cache configuration or nil when it is disabled.
+func (c *MavenContainer) BuildCache() *BuildCache {
+	cache, err := GetBuildCache(*c.GetBuild())
+	if err != nil {
+		slog.Error("Failed to read build cache settings", "error", err)
+		os.Exit(1)
+	}
+	return cache
+}
+
+// Resources returns the limits of the build container.
+func (c *MavenContainer) Resources() Resources {
+	res, err := GetResources(*c.GetBuild())
+	if err != nil {
+		slog.Error("Failed to read container resources", "error", err)
+		os.Exit(1)
+	}
+	return res
+}
+
+// Reactor returns the module selection of the build or nil for the whole reactor.
+func (c *MavenContainer) Reactor() *Reactor {
+	reactor, err := GetReactor(*c.GetBuild())
+	if err != nil {
+		slog.Error("Failed to select reactor modules", "error", err)
+		os.Exit(1)
+	}
+	return reactor
+}
+
+// Unchanged reports whether changed_since selected no reactor module. The
+// build is skipped then, and so are the steps shipping its artifacts.
+func (c *MavenContainer) Unchanged() bool {
+	reactor := c.Reactor()
+	return reactor != nil && reactor.Unchanged
+}
+
+// Settings returns the settings.xml declared in the Custom properties or nil.
+func (c *MavenContainer) Settings() *Settings {
+	settings, err := GetSettings(*c.GetBuild())
+	if err != nil {
+		slog.Error("Failed to read maven settings", "error", err)
+		os.Exit(1)
+	}
+	return settings
 }
 
 func NewProd() build.BuildStep {
@@ -269,69 +718,120 @@ func NewProd() build.BuildStep {
 				slog.Info("No image name skip prod image creation")
 				return "", nil
 			}
+			if c.Unchanged() {
+				slog.Info("No reactor module changed, skipping the prod image", "changed_since", build.Custom.String("changed_since"))
+				return "", nil
+			}
+			if file, err := Artifact(build); err == nil {
+				c.File = u.SrcFile(file)
+			} else {
+				slog.Warn("Failed to find maven artifact", "error", err)
+			}
 			return c.Prod()
 		},
 		ImagesFn: func(build container.Build) []string {
-			return []string{ProdImage(build)}
+			return prodImages(build)
 		},
 		MatchedFn: func(build container.Build) bool {
-			return build.BuildType == container.Maven
+			return build.BuildType == container.Maven && !buildtool.IsGradle(build)
 		},
 		Name_:  "maven-prod",
 		Async_: false,
 	}
 }
 
-func (c *MavenContainer) Prod() (string, error) {
-	opts := types.ContainerConfig{}
-	opts.Image = c.ProdImage
-	opts.Platform = types.AutoPlatform
-	opts.Cmd = []string{"sleep", "300"}
+// ProdSpec describes the prod image, the jar on a JRE image or the war/ear
+// deployed to the selected app server.
+func (c *MavenContainer) ProdSpec() (*ProdSpec, error) {
+	build := *c.GetBuild()
+	spec := NewProdSpec(c.ProdImage)
 
-	err := c.Create(opts)
+	artifact, err := ArtifactType(build)
 	if err != nil {
-		slog.Error("Failed to create container: %s", "error", err)
-		os.Exit(1)
+		return nil, err
+	}
+	if artifact == ArtifactJar {
+		spec.Files = []LayerFile{{Source: c.File.Host(), Target: JarLocation}}
+		ApplyJarConfig(build, spec)
+		return spec, nil
 	}
 
-	err = c.Start()
+	server, err := GetAppServer(build)
 	if err != nil {
-		slog.Error("Failed to start container: %s", "error", err)
-		os.Exit(1)
+		return nil, err
+	}
+	if !server.Deploys(artifact) {
+		return nil, fmt.Errorf("app server %s can not deploy %s artifacts", server.Name, artifact)
 	}
+	for _, config := range build.Custom["server_config"] {
+		spec.Files = append(spec.Files, LayerFile{Source: config, Target: server.ConfigDir + filepath.Base(config)})
+	}
+	spec.Files = append(spec.Files, LayerFile{Source: c.File.Host(), Target: server.DeployDir + filepath.Base(c.File.Host())})
+	spec.Cmd = server.Cmd
+	spec.Ports = []string{server.Port}
+	return spec, nil
+}
 
-	fileName := filepath.Base(c.File.Host())
-	err = c.CopyFileTo(c.File.Host(), "/usr/local/tomcat/webapps/"+fileName)
+// Prod assembles the prod image on the base image pulled into the container
+// runtime and loads it there. The image is smoke tested and pushed from the
+// runtime, with the registry credentials engine-ci configured for the build.
+func (c *MavenContainer) Prod() (string, error) {
+	spec, err := c.ProdSpec()
 	if err != nil {
-		slog.Error("Failed to copy file to container", "error", err, "file", c.File)
-		os.Exit(1)
+		slog.Error("Failed to describe prod image", "error", err)
+		return "", err
+	}
+	test, err := NewSmokeTest(*c.GetBuild(), spec)
+	if err != nil {
+		slog.Error("Failed to configure smoke test", "error", err)
+		return "", err
 	}
 
-	imageId, err := c.Commit(fmt.Sprintf("%s:%s", c.Image, c.ImageTag), "Created from container", "CMD [\"catalina.sh\", \"run\"]")
+	err = c.Pull()
 	if err != nil {
-		slog.Error("Failed to commit container: %s", "error", err)
-		os.Exit(1)
+		slog.Error("Failed to pull base image", "image", spec.Base, "error", err)
+		return "", err
+	}
+	cli, err := runtimeClient(c.GetBuild().Runtime)
+	if err != nil {
+		slog.Error("Failed to connect to the container runtime", "error", err)
+		return "", err
 	}
+	defer cli.Close()
 
-	err = c.Stop()
+	tag := c.ImageTag
+	if tag == "" {
+		tag = "latest"
+	}
+	image := fmt.Sprintf("%s:%s", c.Image, tag)
+	err = spec.Load(cli, image)
 	if err != nil {
-		slog.Error("Failed to stop container: %s", "error", err)
-		os.Exit(1)
+		slog.Error("Failed to assemble prod image", "error", err)
+		return "", err
+	}
+	slog.Info("Loaded prod image", "image", image, "base", spec.Base)
+
+	if test != nil {
+		err = c.Smoke(cli, image, test)
+		if err != nil {
+			return "", err
+		}
 	}
 
 	push := c.GetBuild().Custom.Bool("push", true)
 	if !push {
-		slog.Info("Skipping image push", "image", c.Image, "tag", c.ImageTag)
+		slog.Info("Skipping image push", "image", c.Image, "tag", tag)
 		return "", nil
 	}
-	imageUri := utils.ImageURI(c.GetBuild().Registry, c.Image, c.ImageTag)
-	err = c.Push(imageId, imageUri)
+
+	imageUri := utils.ImageURI(c.GetBuild().Registry, c.Image, tag)
+	err = c.Push(image, imageUri)
 	if err != nil {
-		slog.Error("Failed to push image: %s", "error", err)
-		os.Exit(1)
+		slog.Error("Failed to push image", "error", err)
+		return "", err
 	}
-
-	return c.ID, err
+	slog.Info("Pushed prod image", "image", imageUri)
+	return imageUri, nil
 }
 
 func (c *MavenContainer) Run() (string, error) {
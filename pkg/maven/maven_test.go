@@ -3,11 +3,14 @@ package maven
 import (
 	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/containifyci/engine-ci/pkg/container"
 	"github.com/containifyci/engine-ci/pkg/cri"
 	"github.com/containifyci/engine-ci/pkg/cri/critest"
+	"github.com/containifyci/engine-ci/pkg/cri/types"
 	"github.com/containifyci/engine-ci/pkg/logger"
 	"github.com/containifyci/engine-ci/pkg/network"
 	"github.com/stretchr/testify/assert"
@@ -42,6 +45,7 @@ func InitTest(t *testing.T) *container.Build {
 
 func TestNew(t *testing.T) {
 	build := InitTest(t)
+	build.File = "target/app.war"
 
 	mc := new(build)
 	matches := Matches(*build)
@@ -50,11 +54,12 @@ func TestNew(t *testing.T) {
 	assert.False(t, mc.IsAsync())
 	assert.Equal(t, "test-image", mc.Image)
 	assert.Equal(t, "v17", mc.Version)
-	assert.Equal(t, []string{"containifyci/maven-3-eclipse-temurin-v17-alpine:cdbe73779492603b08a3e880bf25754e3a8e865811c51c0b45e2c5edfc5a8476", "tomcat:latest"}, Images(*build))
+	assert.Equal(t, []string{"containifyci/maven-3.9.11-eclipse-temurin-v17:15bd0eac7b3716edef701cd087d96645b33ef27c27191ffdbb9025eb24ed59ce", "tomcat:latest"}, Images(*build))
 }
 
 func TestNewProd(t *testing.T) {
 	build := InitTest(t)
+	build.File = "target/app.war"
 
 	mc := NewProd()
 	assert.Equal(t, "maven-prod", mc.Name())
@@ -79,7 +84,7 @@ func TestPull(t *testing.T) {
 
 func TestBuildLinuxPodman(t *testing.T) {
 	expectedEnvs := []string{
-		"MAVEN_OPTS=-Xms512m -Xmx512m -XX:MaxDirectMemorySize=512m",
+		"MAVEN_OPTS=-Xms971m -Xmx971m -XX:MaxDirectMemorySize=971m",
 		"SSH_AUTH_SOCK=/tmp/ssh-auth.sock",
 		"CONTAINIFYCI_HOST=localhost",
 		"DOCKER_HOST=unix://var/run/podman.sock",
@@ -101,7 +106,7 @@ func TestBuildLinuxPodman(t *testing.T) {
 	assert.NoError(t, err)
 
 	if v, ok := cRuntime.(*critest.MockContainerManager); ok {
-		img := "containifyci/maven-3-eclipse-temurin-v17-alpine:cdbe73779492603b08a3e880bf25754e3a8e865811c51c0b45e2c5edfc5a8476"
+		img := "containifyci/maven-3.9.11-eclipse-temurin-v17:15bd0eac7b3716edef701cd087d96645b33ef27c27191ffdbb9025eb24ed59ce"
 		require.Len(t, v.ContainerLogsEntries[img], 2)
 		assert.Equal(t, []string{"container starting", "container running"}, v.ContainerLogsEntries[img])
 
@@ -117,7 +122,7 @@ func TestBuildLinuxPodman(t *testing.T) {
 
 func TestBuildDarwinPodman(t *testing.T) {
 	expectedEnvs := []string{
-		"MAVEN_OPTS=-Xms512m -Xmx512m -XX:MaxDirectMemorySize=512m",
+		"MAVEN_OPTS=-Xms971m -Xmx971m -XX:MaxDirectMemorySize=971m",
 		"TC_HOST=host.containers.internal",
 		"TESTCONTAINERS_HOST_OVERRIDE=host.containers.internal",
 		"CONTAINIFYCI_HOST=localhost",
@@ -138,21 +143,21 @@ func TestBuildDarwinPodman(t *testing.T) {
 	assert.NoError(t, err)
 
 	if v, ok := cRuntime.(*critest.MockContainerManager); ok {
-		v.Errors["containifyci/maven-3-eclipse-temurin-v17-alpine:cdbe73779492603b08a3e880bf25754e3a8e865811c51c0b45e2c5edfc5a8476"] = errors.New("image not found")
+		v.Errors["containifyci/maven-3.9.11-eclipse-temurin-v17:15bd0eac7b3716edef701cd087d96645b33ef27c27191ffdbb9025eb24ed59ce"] = errors.New("image not found")
 
 		id, err := mc.Run()
 		assert.NoError(t, err)
 		assert.NotEmpty(t, id)
 
-		img := "containifyci/maven-3-eclipse-temurin-v17-alpine:cdbe73779492603b08a3e880bf25754e3a8e865811c51c0b45e2c5edfc5a8476"
+		img := "containifyci/maven-3.9.11-eclipse-temurin-v17:15bd0eac7b3716edef701cd087d96645b33ef27c27191ffdbb9025eb24ed59ce"
 		assert.Len(t, v.ContainerLogsEntries[img], 2)
 		assert.Equal(t, []string{"container starting", "container running"}, v.ContainerLogsEntries[img])
 
 		assert.Equal(t, "started", v.GetContainerByImage(img).State)
 		assert.Equal(t, []string{"sh", "/tmp/script.sh"}, v.GetContainerByImage(img).Opts.Cmd)
-		assert.Equal(t, "#!/bin/sh\nset -xe\ncd .\nmvn --batch-mode package\n", v.GetContainerByImage(img).Opts.Script)
+		assert.Contains(t, v.GetContainerByImage(img).Opts.Script, "\n{ mvn --batch-mode --threads 2 package || echo $? > /tmp/containifyci/build.status; } 2>&1 | tee /tmp/containifyci/build.log\n")
 		assert.Equal(t, "/src", v.GetContainerByImage(img).Opts.WorkingDir)
-		assert.Equal(t, "containifyci/maven-3-eclipse-temurin-v17-alpine:cdbe73779492603b08a3e880bf25754e3a8e865811c51c0b45e2c5edfc5a8476", v.GetContainerByImage(img).Opts.Image)
+		assert.Equal(t, "containifyci/maven-3.9.11-eclipse-temurin-v17:15bd0eac7b3716edef701cd087d96645b33ef27c27191ffdbb9025eb24ed59ce", v.GetContainerByImage(img).Opts.Image)
 		assert.Equal(t, int64(4073741824), v.GetContainerByImage(img).Opts.Memory)
 		assert.Equal(t, uint64(2048), v.GetContainerByImage(img).Opts.CPU)
 
@@ -161,39 +166,172 @@ func TestBuildDarwinPodman(t *testing.T) {
 			assert.Contains(t, v.GetContainerByImage(img).Opts.Env, env)
 		}
 
-		//expect 3 images opnejdk image and maven-3-eclipse-temurin-17-alpine twice for both platforms amd64 and arm64
+		//expect 3 images opnejdk image and maven-3.9.11-eclipse-temurin-v17 twice for both platforms amd64 and arm64
 		assert.Len(t, v.Images, 3)
 		assert.NotNil(t, v.Images["tomcat:latest"])
-		assert.Equal(t, "linux/amd64", v.Images["containifyci/maven-3-eclipse-temurin-v17-alpine:cdbe73779492603b08a3e880bf25754e3a8e865811c51c0b45e2c5edfc5a8476-linux/amd64"].Opts.Platform.String())
-		assert.Equal(t, "linux/arm64", v.Images["containifyci/maven-3-eclipse-temurin-v17-alpine:cdbe73779492603b08a3e880bf25754e3a8e865811c51c0b45e2c5edfc5a8476-linux/arm64"].Opts.Platform.String())
+		assert.Equal(t, "linux/amd64", v.Images["containifyci/maven-3.9.11-eclipse-temurin-v17:15bd0eac7b3716edef701cd087d96645b33ef27c27191ffdbb9025eb24ed59ce-linux/amd64"].Opts.Platform.String())
+		assert.Equal(t, "linux/arm64", v.Images["containifyci/maven-3.9.11-eclipse-temurin-v17:15bd0eac7b3716edef701cd087d96645b33ef27c27191ffdbb9025eb24ed59ce-linux/arm64"].Opts.Platform.String())
 	}
 }
 
+func TestBuildOffline(t *testing.T) {
+	arg := InitTest(t)
+	arg.Platform.Host.OS = "linux"
+	arg.Custom["offline"] = []string{"true"}
+	engine := useFakeEngine(t)
+
+	mc := new(arg)
+	require.NoError(t, mc.Build())
+	// the build container is cut off the network before it starts
+	assert.Len(t, engine.Disconnected, 1)
+
+	cRuntime, err := cri.InitContainerRuntime()
+	assert.NoError(t, err)
+	if v, ok := cRuntime.(*critest.MockContainerManager); ok {
+		offline := v.GetContainerByImage(MavenImage(*arg))
+		require.Len(t, offline.Opts.Cmd, 3)
+		assert.Contains(t, offline.Opts.Cmd[2], "mvn --batch-mode --offline ")
+	}
+
+	engine.Disconnected = map[string]bool{}
+	engine.NetworkErr = errors.New("network mode host")
+	opts, cleanup := mc.BuildOpts()
+	defer cleanup()
+	err = mc.OfflineBuild(opts, PrefetchScript(mc.NewBuildScript()))
+	assert.ErrorContains(t, err, "the container runtime did not disable its network")
+}
+
+func TestReadOnlyCacheMount(t *testing.T) {
+	arg := InitTest(t)
+	arg.Folder = t.TempDir()
+	arg.Custom["cache_read_only"] = []string{"true"}
+	arg.Custom["publish"] = []string{"true"}
+	cache := t.TempDir()
+	t.Setenv("MAVEN_HOME", cache)
+	require.NoError(t, os.WriteFile(filepath.Join(cache, "settings.xml"), []byte("<settings/>"), 0o644))
+
+	var sources []string
+	previous := buildingContainer
+	buildingContainer = func(c *MavenContainer, opts types.ContainerConfig) error {
+		for _, volume := range opts.Volumes {
+			if volume.Target == CacheLocation {
+				sources = append(sources, volume.Source)
+				assert.FileExists(t, filepath.Join(volume.Source, "settings.xml"))
+			}
+		}
+		return nil
+	}
+	t.Cleanup(func() { buildingContainer = previous })
+
+	mc := new(arg)
+	steps := []struct {
+		name string
+		run  func() error
+	}{
+		{"maven", mc.Build},
+		{"maven-lint", mc.Lint},
+		{"maven-format", mc.Format},
+		{"maven-publish", mc.Publish},
+	}
+	for _, step := range steps {
+		sources = nil
+		require.NoError(t, step.run(), step.name)
+		// every step mounts a copy of the cache, removed after the step
+		require.Len(t, sources, 1, step.name)
+		assert.NotEqual(t, cache, sources[0], step.name)
+		assert.NoDirExists(t, sources[0], step.name)
+	}
+
+	delete(arg.Custom, "cache_read_only")
+	opts, cleanup := mc.BuildOpts()
+	cleanup()
+	assert.Contains(t, opts.Volumes, types.Volume{Type: "bind", Source: cache, Target: CacheLocation})
+}
+
 func TestProd(t *testing.T) {
 	arg := InitTest(t)
 	arg.Platform.Host.OS = "darwin"
 	arg.Runtime = "podman"
+	runtime := useFakeEngine(t, "tomcat:latest")
+
+	arg.Registry = "registry.example.com"
+	arg.ImageTag = "1.0.0"
+	arg.File = filepath.Join(t.TempDir(), "app.war")
+	require.NoError(t, os.WriteFile(arg.File, []byte("war"), 0o644))
 
 	mc := NewProd()
 
+	image, err := mc.RunWithBuild(*arg)
+	require.NoError(t, err)
+	assert.Equal(t, "registry.example.com/test-image:1.0.0", image)
+	// the prod image is loaded into the runtime and pushed from there
+	assert.Contains(t, runtime.Images, "index.docker.io/library/test-image:1.0.0")
+
 	cRuntime, err := cri.InitContainerRuntime()
 	assert.NoError(t, err)
-
 	if v, ok := cRuntime.(*critest.MockContainerManager); ok {
-		id, err := mc.RunWithBuild(*arg)
-		assert.NoError(t, err)
-		assert.NotEmpty(t, id)
+		// the prod image is assembled without running a container
+		assert.Empty(t, v.ContainerLogsEntries["tomcat:latest"])
+		assert.NotNil(t, v.Images["tomcat:latest"])
+	}
+
+	// without push the image is left in the runtime
+	delete(runtime.Images, "index.docker.io/library/test-image:1.0.0")
+	arg.Custom["push"] = []string{"false"}
+	image, err = mc.RunWithBuild(*arg)
+	require.NoError(t, err)
+	assert.Empty(t, image)
+	assert.Contains(t, runtime.Images, "index.docker.io/library/test-image:1.0.0")
+}
 
-		img := "tomcat:latest"
-		assert.Len(t, v.ContainerLogsEntries[img], 3)
-		assert.Equal(t, []string{"container starting", "container running", "container stopped"}, v.ContainerLogsEntries[img])
+func TestBuildScriptFromCustom(t *testing.T) {
+	arg := InitTest(t)
+	arg.Custom["goals"] = []string{"clean verify"}
+	arg.Custom["profiles"] = []string{"ci,it"}
+	arg.Custom["maven_properties"] = []string{"revision=2.0.0"}
+	arg.Custom["maven_args"] = []string{"-U"}
+	arg.Custom["skip_tests"] = []string{"true"}
 
-		assert.Equal(t, "stopped", v.GetContainerByImage(img).State)
-		assert.Equal(t, []string{"sleep", "300"}, v.GetContainerByImage(img).Opts.Cmd)
-		assert.Equal(t, "tomcat:latest", v.GetContainerByImage(img).Opts.Image)
+	mc := new(arg)
+	assert.Equal(t, "#!/bin/sh\nset -xe\ncd .\nmvn --batch-mode --threads 2 clean verify -Pci,it -Drevision=2.0.0 -DskipTests -U\n", mc.BuildScript())
+}
 
-		fmt.Printf("Envs %+v\n", v.GetContainerByImage(img).Opts.Env)
-	} else {
-		t.Fatal("Container runtime is not a MockContainerManager")
-	}
+func TestGetVersionFromPom(t *testing.T) {
+	arg := InitTest(t)
+	delete(arg.Custom, "from")
+	arg.Folder = t.TempDir()
+	writePom(t, arg.Folder, `<project><properties><maven.compiler.release>21</maven.compiler.release></properties></project>`)
+
+	assert.Equal(t, "v21", GetVersion(*arg))
+	assert.True(t, Matches(*arg))
+
+	// java 12 has no builder and is built on the oldest newer one
+	writePom(t, arg.Folder, `<project><properties><maven.compiler.release>12</maven.compiler.release></properties></project>`)
+	assert.Equal(t, "v17", GetVersion(*arg))
+	assert.True(t, Matches(*arg))
+
+	// java newer than every builder is built on the newest one instead of skipping the step
+	writePom(t, arg.Folder, `<project><properties><maven.compiler.release>99</maven.compiler.release></properties></project>`)
+	assert.Equal(t, "v25", GetVersion(*arg))
+	assert.True(t, Matches(*arg))
+
+	// the pom is read once per build
+	key := versionKey(*arg)
+	builderVersions.Store(key, "v21")
+	assert.Equal(t, "v21", GetVersion(*arg))
+	builderVersions.Delete(key)
+}
+
+func TestMavenVersion(t *testing.T) {
+	arg := InitTest(t)
+	mc := new(arg)
+
+	assert.Equal(t, DEFAULT_MAVEN_DIST_VERSION, mc.MavenVersion(nil))
+	arg.Custom["maven_version"] = []string{"3.8.8"}
+	assert.Equal(t, "3.8.8", mc.MavenVersion(nil))
+	// the wrapper pins the version of the build
+	wrapper := &Wrapper{DistributionURL: "https://repo.maven.apache.org/maven2/org/apache/maven/apache-maven/3.6.3/apache-maven-3.6.3-bin.zip"}
+	assert.Equal(t, "3.6.3", mc.MavenVersion(wrapper))
+	arg.Custom["mvnd"] = []string{"true"}
+	assert.Empty(t, mc.MavenVersion(wrapper))
 }
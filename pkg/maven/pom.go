@@ -0,0 +1,194 @@
+package maven
+
+import (
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const PomFile = "pom.xml"
+
+// Pom is the subset of a Maven project model engine-java inspects.
+type Pom struct {
+	Parent     *PomParent    `xml:"parent"`
+	GroupID    string        `xml:"groupId"`
+	ArtifactID string        `xml:"artifactId"`
+	Version    string        `xml:"version"`
+	Packaging  string        `xml:"packaging"`
+	FinalName  string        `xml:"build>finalName"`
+	Properties PomProperties `xml:"properties"`
+	Plugins    []PomPlugin   `xml:"build>plugins>plugin"`
+	Managed    []PomPlugin   `xml:"build>pluginManagement>plugins>plugin"`
+	Modules    []string      `xml:"modules>module"`
+}
+
+type PomParent struct {
+	GroupID      string  `xml:"groupId"`
+	ArtifactID   string  `xml:"artifactId"`
+	Version      string  `xml:"version"`
+	RelativePath *string `xml:"relativePath"`
+}
+
+type PomPlugin struct {
+	GroupID       string `xml:"groupId"`
+	ArtifactID    string `xml:"artifactId"`
+	Configuration struct {
+		Release    string `xml:"release"`
+		Target     string `xml:"target"`
+		Toolchains struct {
+			JDK struct {
+				Version string `xml:"version"`
+			} `xml:"jdk"`
+		} `xml:"toolchains"`
+	} `xml:"configuration"`
+}
+
+// PomProperties collects the free-form <properties> of a pom.
+type PomProperties map[string]string
+
+func (p *PomProperties) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	*p = PomProperties{}
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			var value string
+			if err := d.DecodeElement(&value, &t); err != nil {
+				return err
+			}
+			(*p)[t.Name.Local] = strings.TrimSpace(value)
+		case xml.EndElement:
+			return nil
+		}
+	}
+}
+
+// ReadPom parses the pom.xml at file and merges the properties of every
+// parent pom available locally, the child's properties taking precedence.
+func ReadPom(file string) (*Pom, error) {
+	pom, err := parsePom(file)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{}
+	current, dir := pom, filepath.Dir(file)
+	for current.Parent != nil {
+		relative := "../" + PomFile
+		if current.Parent.RelativePath != nil {
+			relative = *current.Parent.RelativePath
+		}
+		if relative == "" {
+			break
+		}
+		parentFile := filepath.Join(dir, relative)
+		if info, err := os.Stat(parentFile); err == nil && info.IsDir() {
+			parentFile = filepath.Join(parentFile, PomFile)
+		}
+		parentFile, _ = filepath.Abs(parentFile)
+		if seen[parentFile] {
+			break
+		}
+		seen[parentFile] = true
+
+		parent, err := parsePom(parentFile)
+		if err != nil {
+			// the parent is only resolvable from a repository
+			break
+		}
+		if parent.ArtifactID != current.Parent.ArtifactID {
+			break
+		}
+		for k, v := range parent.Properties {
+			if _, ok := pom.Properties[k]; !ok {
+				pom.Properties[k] = v
+			}
+		}
+		pom.Plugins = append(pom.Plugins, parent.Plugins...)
+		pom.Managed = append(pom.Managed, parent.Managed...)
+		current, dir = parent, filepath.Dir(parentFile)
+	}
+	return pom, nil
+}
+
+func parsePom(file string) (*Pom, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var pom Pom
+	if err := xml.Unmarshal(data, &pom); err != nil {
+		return nil, err
+	}
+	if pom.Properties == nil {
+		pom.Properties = PomProperties{}
+	}
+	return &pom, nil
+}
+
+var propertyRef = regexp.MustCompile(`\$\{([^}]+)\}`)
+
+// Resolve expands ${property} references using the pom properties.
+func (p *Pom) Resolve(value string) string {
+	for range 10 {
+		expanded := propertyRef.ReplaceAllStringFunc(value, func(ref string) string {
+			if v, ok := p.Properties[ref[2:len(ref)-1]]; ok {
+				return v
+			}
+			return ref
+		})
+		if expanded == value {
+			break
+		}
+		value = expanded
+	}
+	return value
+}
+
+// JavaVersion returns the Java feature version the project compiles for and
+// the pom setting it was read from.
+func (p *Pom) JavaVersion() (int, string) {
+	for _, key := range []string{"maven.compiler.release", "maven.compiler.target", "java.version"} {
+		if v, ok := parseJavaVersion(p.Resolve(p.Properties[key])); ok {
+			return v, key
+		}
+	}
+	for _, plugin := range append(p.Plugins, p.Managed...) {
+		if plugin.ArtifactID == "maven-compiler-plugin" {
+			if v, ok := parseJavaVersion(p.Resolve(plugin.Configuration.Release)); ok {
+				return v, "maven-compiler-plugin release"
+			}
+			if v, ok := parseJavaVersion(p.Resolve(plugin.Configuration.Target)); ok {
+				return v, "maven-compiler-plugin target"
+			}
+		}
+		if plugin.ArtifactID == "maven-toolchains-plugin" {
+			if v, ok := parseJavaVersion(p.Resolve(plugin.Configuration.Toolchains.JDK.Version)); ok {
+				return v, "maven-toolchains-plugin jdk"
+			}
+		}
+	}
+	return 0, ""
+}
+
+var javaVersionPrefix = regexp.MustCompile(`^\[?\s*(1\.)?(\d+)`)
+
+// parseJavaVersion understands plain feature versions (21), the legacy
+// 1.x notation (1.8) and the lower bound of toolchain ranges ([17,)).
+func parseJavaVersion(value string) (int, bool) {
+	m := javaVersionPrefix.FindStringSubmatch(strings.TrimSpace(value))
+	if m == nil {
+		return 0, false
+	}
+	v, err := strconv.Atoi(m[2])
+	if err != nil || v == 0 {
+		return 0, false
+	}
+	return v, true
+}
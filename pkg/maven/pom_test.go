@@ -0,0 +1,108 @@
+package maven
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writePom(t *testing.T, dir, content string) {
+	require.NoError(t, os.MkdirAll(dir, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, PomFile), []byte(content), 0o644))
+}
+
+func TestJavaVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		pom     string
+		version int
+		source  string
+	}{
+		{
+			name:    "release",
+			pom:     `<project><properties><maven.compiler.release>21</maven.compiler.release><maven.compiler.target>17</maven.compiler.target></properties></project>`,
+			version: 21,
+			source:  "maven.compiler.release",
+		},
+		{
+			name:    "legacy notation",
+			pom:     `<project><properties><maven.compiler.target>1.8</maven.compiler.target></properties></project>`,
+			version: 8,
+			source:  "maven.compiler.target",
+		},
+		{
+			name:    "property reference",
+			pom:     `<project><properties><jdk>17</jdk><java.version>${jdk}</java.version></properties></project>`,
+			version: 17,
+			source:  "java.version",
+		},
+		{
+			name: "compiler plugin",
+			pom: `<project><build><plugins><plugin>
+				<artifactId>maven-compiler-plugin</artifactId>
+				<configuration><release>21</release></configuration>
+			</plugin></plugins></build></project>`,
+			version: 21,
+			source:  "maven-compiler-plugin release",
+		},
+		{
+			name: "toolchain",
+			pom: `<project><build><plugins><plugin>
+				<artifactId>maven-toolchains-plugin</artifactId>
+				<configuration><toolchains><jdk><version>[17,)</version></jdk></toolchains></configuration>
+			</plugin></plugins></build></project>`,
+			version: 17,
+			source:  "maven-toolchains-plugin jdk",
+		},
+		{
+			name: "none",
+			pom:  `<project><properties><foo>bar</foo></properties></project>`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writePom(t, dir, tt.pom)
+
+			pom, err := ReadPom(filepath.Join(dir, PomFile))
+			require.NoError(t, err)
+			version, source := pom.JavaVersion()
+			assert.Equal(t, tt.version, version)
+			assert.Equal(t, tt.source, source)
+		})
+	}
+}
+
+func TestReadPomInheritsParentProperties(t *testing.T) {
+	root := t.TempDir()
+	writePom(t, root, `<project>
+		<artifactId>parent</artifactId>
+		<packaging>pom</packaging>
+		<properties><java.version>21</java.version><encoding>UTF-8</encoding></properties>
+	</project>`)
+	writePom(t, filepath.Join(root, "service"), `<project>
+		<parent><artifactId>parent</artifactId></parent>
+		<artifactId>service</artifactId>
+		<packaging>jar</packaging>
+		<properties><encoding>ISO-8859-1</encoding></properties>
+	</project>`)
+
+	pom, err := ReadPom(filepath.Join(root, "service", PomFile))
+	require.NoError(t, err)
+	assert.Equal(t, "jar", pom.Packaging)
+	assert.Equal(t, "ISO-8859-1", pom.Properties["encoding"])
+
+	version, source := pom.JavaVersion()
+	assert.Equal(t, 21, version)
+	assert.Equal(t, "java.version", source)
+	assert.Equal(t, "v21", DetectVersion(filepath.Join(root, "service"), []int{17, 21}))
+}
+
+func TestDetectVersion(t *testing.T) {
+	assert.Equal(t, "v17", DetectVersion("../../testdata/hello-world-servlet", []int{17, 21}))
+	assert.Equal(t, "v11", DetectVersion("../../testdata/hello-world-servlet", []int{8, 11, 17}))
+	assert.Equal(t, "", DetectVersion(t.TempDir(), []int{17, 21}))
+}
@@ -0,0 +1,167 @@
+package maven
+
+import (
+	"archive/tar"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/daemon"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+)
+
+// LayerFile is a host file added to the prod image at Target.
+type LayerFile struct {
+	Source string
+	Target string
+}
+
+// ProdSpec describes a prod image as a single layer appended to a base image.
+// No container is started to assemble it and the layer is reproducible for
+// the same inputs.
+type ProdSpec struct {
+	Base  string
+	Files []LayerFile
+
+	// Entrypoint and Cmd replace the ones of the base when set.
+	Entrypoint []string
+	Cmd        []string
+	Env        []string
+	Ports      []string
+
+	// Created is the timestamp of the layer entries and the image, the
+	// SOURCE_DATE_EPOCH or the unix epoch by default.
+	Created time.Time
+}
+
+// NewProdSpec creates a spec for base.
+func NewProdSpec(base string) *ProdSpec {
+	return &ProdSpec{
+		Base:    base,
+		Created: sourceDateEpoch(),
+	}
+}
+
+func sourceDateEpoch() time.Time {
+	if epoch, err := strconv.ParseInt(os.Getenv("SOURCE_DATE_EPOCH"), 10, 64); err == nil {
+		return time.Unix(epoch, 0).UTC()
+	}
+	return time.Unix(0, 0).UTC()
+}
+
+// Layer returns the tar layer holding the files of the spec.
+func (s *ProdSpec) Layer() (v1.Layer, error) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, file := range s.Files {
+		data, err := os.ReadFile(file.Source)
+		if err != nil {
+			return nil, err
+		}
+		err = tw.WriteHeader(&tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     strings.TrimPrefix(path.Clean(file.Target), "/"),
+			Mode:     0o644,
+			Size:     int64(len(data)),
+			ModTime:  s.Created,
+			Format:   tar.FormatPAX,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if _, err := tw.Write(data); err != nil {
+			return nil, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	layer := buf.Bytes()
+	return tarball.LayerFromOpener(func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(layer)), nil
+	})
+}
+
+// Mutate appends the layer to base and applies the image configuration.
+func (s *ProdSpec) Mutate(base v1.Image, layer v1.Layer) (v1.Image, error) {
+	img, err := mutate.Append(base, mutate.Addendum{
+		Layer: layer,
+		History: v1.History{
+			Created:   v1.Time{Time: s.Created},
+			CreatedBy: "engine-java maven-prod",
+			Comment:   "Created from " + s.Base,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	cfg, err := img.ConfigFile()
+	if err != nil {
+		return nil, err
+	}
+	config := *cfg.Config.DeepCopy()
+	if len(s.Entrypoint) > 0 {
+		config.Entrypoint = s.Entrypoint
+		config.Cmd = nil
+	}
+	if len(s.Cmd) > 0 {
+		config.Cmd = s.Cmd
+	}
+	config.Env = append(config.Env, s.Env...)
+	for _, port := range s.Ports {
+		if config.ExposedPorts == nil {
+			config.ExposedPorts = map[string]struct{}{}
+		}
+		if !strings.Contains(port, "/") {
+			port += "/tcp"
+		}
+		config.ExposedPorts[port] = struct{}{}
+	}
+	img, err = mutate.Config(img, config)
+	if err != nil {
+		return nil, err
+	}
+	return mutate.CreatedAt(img, v1.Time{Time: s.Created})
+}
+
+// Image assembles the prod image on the copy of the base image in the
+// container runtime, engine-ci pulls it with the registry credentials of the build.
+func (s *ProdSpec) Image(cli daemon.Client) (v1.Image, error) {
+	ref, err := name.ParseReference(s.Base)
+	if err != nil {
+		return nil, err
+	}
+	base, err := daemon.Image(ref, daemon.WithClient(cli), daemon.WithUnbufferedOpener())
+	if err != nil {
+		return nil, fmt.Errorf("failed to read base image %s: %w", s.Base, err)
+	}
+	layer, err := s.Layer()
+	if err != nil {
+		return nil, err
+	}
+	return s.Mutate(base, layer)
+}
+
+// Load assembles the prod image and loads it into the container runtime as
+// tag, from where it is smoke tested and pushed.
+func (s *ProdSpec) Load(cli daemon.Client, tag string) error {
+	ref, err := name.NewTag(tag)
+	if err != nil {
+		return err
+	}
+	img, err := s.Image(cli)
+	if err != nil {
+		return err
+	}
+	_, err = daemon.Write(ref, img, daemon.WithClient(cli))
+	return err
+}
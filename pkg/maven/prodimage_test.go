@@ -0,0 +1,99 @@
+package maven
+
+import (
+	"archive/tar"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testSpec(t *testing.T, base string) *ProdSpec {
+	file := filepath.Join(t.TempDir(), "app.jar")
+	require.NoError(t, os.WriteFile(file, []byte("jar"), 0o644))
+
+	spec := NewProdSpec(base)
+	spec.Files = []LayerFile{{Source: file, Target: JarLocation}}
+	return spec
+}
+
+func TestProdSpecLayer(t *testing.T) {
+	spec := testSpec(t, "eclipse-temurin:21-jre-jammy")
+
+	layer, err := spec.Layer()
+	require.NoError(t, err)
+	rc, err := layer.Uncompressed()
+	require.NoError(t, err)
+	defer rc.Close()
+
+	tr := tar.NewReader(rc)
+	hdr, err := tr.Next()
+	require.NoError(t, err)
+	assert.Equal(t, "opt/app.jar", hdr.Name)
+	assert.Equal(t, int64(0o644), hdr.Mode)
+	assert.Equal(t, time.Unix(0, 0).UTC(), hdr.ModTime.UTC())
+	data, err := io.ReadAll(tr)
+	require.NoError(t, err)
+	assert.Equal(t, "jar", string(data))
+
+	// the layer is reproducible
+	again, err := spec.Layer()
+	require.NoError(t, err)
+	d1, err := layer.Digest()
+	require.NoError(t, err)
+	d2, err := again.Digest()
+	require.NoError(t, err)
+	assert.Equal(t, d1, d2)
+}
+
+func TestProdSpecMutate(t *testing.T) {
+	spec := testSpec(t, "eclipse-temurin:21-jre-jammy")
+	spec.Entrypoint = []string{"java", "-jar", JarLocation}
+	spec.Env = []string{"JAVA_TOOL_OPTIONS=-Xmx1g"}
+	spec.Ports = []string{"8080"}
+
+	base, err := random.Image(64, 1)
+	require.NoError(t, err)
+	layer, err := spec.Layer()
+	require.NoError(t, err)
+
+	img, err := spec.Mutate(base, layer)
+	require.NoError(t, err)
+
+	layers, err := img.Layers()
+	require.NoError(t, err)
+	assert.Len(t, layers, 2)
+
+	cfg, err := img.ConfigFile()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"java", "-jar", "/opt/app.jar"}, cfg.Config.Entrypoint)
+	assert.Contains(t, cfg.Config.Env, "JAVA_TOOL_OPTIONS=-Xmx1g")
+	assert.Contains(t, cfg.Config.ExposedPorts, "8080/tcp")
+	assert.Equal(t, time.Unix(0, 0).UTC(), cfg.Created.Time.UTC())
+}
+
+func TestProdSpecLoad(t *testing.T) {
+	runtime := useFakeEngine(t, "tomcat:latest")
+	spec := testSpec(t, "tomcat:latest")
+	spec.Cmd = []string{"catalina.sh", "run"}
+
+	require.NoError(t, spec.Load(runtime, "app:1.0.0"))
+
+	img, ok := runtime.Images["index.docker.io/library/app:1.0.0"]
+	require.True(t, ok)
+	layers, err := img.Layers()
+	require.NoError(t, err)
+	assert.Len(t, layers, 2)
+	cfg, err := img.ConfigFile()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"catalina.sh", "run"}, cfg.Config.Cmd)
+
+	// the base image is taken from the runtime, engine-ci pulls it
+	err = NewProdSpec("jetty:latest").Load(runtime, "app:1.0.0")
+	assert.ErrorContains(t, err, "failed to read base image jetty:latest")
+}
@@ -0,0 +1,129 @@
+package maven
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/containifyci/engine-ci/pkg/build"
+	"github.com/containifyci/engine-ci/pkg/container"
+)
+
+// Server ids of the publish repositories, their credentials are read from
+// maven_username.<id> and maven_password.<id> like the ones of any repository.
+const (
+	ReleasesID  = "releases"
+	SnapshotsID = "snapshots"
+)
+
+// Publish holds the repositories artifacts are deployed to. Empty urls leave
+// the distributionManagement of the pom in charge.
+type Publish struct {
+	Releases  string
+	Snapshots string
+}
+
+func NewPublish() build.BuildStep {
+	return build.Stepper{
+		RunFn: func(build container.Build) (string, error) {
+			container := new(&build)
+			if container.Unchanged() {
+				slog.Info("No reactor module changed, skipping the publish", "changed_since", build.Custom.String("changed_since"))
+				return "", nil
+			}
+			return container.RunPublish()
+		},
+		MatchedFn: func(build container.Build) bool {
+			publish, err := GetPublish(build)
+			if err != nil {
+				slog.Warn("Not publishing with maven", "error", err)
+				return false
+			}
+			return publish != nil && Matches(build)
+		},
+		ImagesFn: func(build container.Build) []string {
+			return []string{MavenImage(build)}
+		},
+		Name_:  "maven-publish",
+		Async_: false,
+	}
+}
+
+// GetPublish reads the publish_releases and publish_snapshots repository urls.
+// Setting publish to true deploys to the repositories of the pom. It returns
+// nil when publishing is not configured.
+func GetPublish(build container.Build) (*Publish, error) {
+	publish := &Publish{
+		Releases:  build.Custom.String("publish_releases"),
+		Snapshots: build.Custom.String("publish_snapshots"),
+	}
+	if publish.Releases == "" && publish.Snapshots == "" && !build.Custom.Bool("publish", false) {
+		return nil, nil
+	}
+	for key, value := range map[string]string{"publish_releases": publish.Releases, "publish_snapshots": publish.Snapshots} {
+		if value == "" {
+			continue
+		}
+		if err := validateRepositoryURL(value); err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", key, err)
+		}
+	}
+	return publish, nil
+}
+
+// Properties point the maven-deploy-plugin to the configured repositories.
+func (p *Publish) Properties() []string {
+	var props []string
+	if p.Releases != "" {
+		props = append(props, fmt.Sprintf("altReleaseDeploymentRepository=%s::%s", ReleasesID, p.Releases))
+	}
+	if p.Snapshots != "" {
+		props = append(props, fmt.Sprintf("altSnapshotDeploymentRepository=%s::%s", SnapshotsID, p.Snapshots))
+	}
+	return props
+}
+
+// PublishScript runs deploy without the tests the maven step already ran.
+func (c *MavenContainer) PublishScript(publish *Publish) string {
+	bs := c.NewBuildScript()
+	bs.Goals = []string{"deploy"}
+	bs.SkipTests = true
+	// deploying needs the network
+	bs.Offline = false
+	bs.Properties = append(bs.Properties[:len(bs.Properties):len(bs.Properties)], publish.Properties()...)
+	return Script(bs)
+}
+
+func (c *MavenContainer) Publish() error {
+	publish, err := GetPublish(*c.GetBuild())
+	if err != nil {
+		slog.Error("Failed to read publish repositories", "error", err)
+		os.Exit(1)
+	}
+
+	opts, cleanup := c.BuildOpts()
+	opts.Script = c.PublishScript(publish)
+
+	slog.Info("Publishing maven artifacts", "releases", publish.Releases, "snapshots", publish.Snapshots)
+	err = buildingContainer(c, opts)
+	cleanup()
+	if err != nil {
+		slog.Error("Failed to publish maven artifacts", "error", err)
+		os.Exit(1)
+	}
+	return nil
+}
+
+func (c *MavenContainer) RunPublish() (string, error) {
+	err := c.BuildMavenImage()
+	if err != nil {
+		slog.Error("Failed to build maven image", "error", err)
+		return "", err
+	}
+
+	err = c.Publish()
+	if err != nil {
+		return "", err
+	}
+	return c.ID, nil
+}
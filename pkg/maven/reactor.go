@@ -0,0 +1,176 @@
+package maven
+
+import (
+	"fmt"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/containifyci/engine-ci/pkg/container"
+)
+
+// Reactor selects the modules of a multi-module build.
+type Reactor struct {
+	// Modules are passed to -pl, as folders relative to the project or :artifactId.
+	Modules            []string
+	AlsoMake           bool
+	AlsoMakeDependents bool
+	// Unchanged is set when the automatic selection found no changed module.
+	Unchanged bool
+}
+
+// GetReactor reads the module selection: modules, also_make and
+// also_make_dependents, or changed_since for the modules changed since a git
+// ref, including their dependents. It returns nil to build the whole reactor.
+func GetReactor(build container.Build) (*Reactor, error) {
+	base := build.Custom.String("changed_since")
+	reactor := &Reactor{
+		Modules:            customList(&build, "modules"),
+		AlsoMake:           build.Custom.Bool("also_make", base != ""),
+		AlsoMakeDependents: build.Custom.Bool("also_make_dependents", base != ""),
+	}
+	if base != "" {
+		if len(reactor.Modules) > 0 {
+			return nil, fmt.Errorf("modules and changed_since cannot be combined")
+		}
+		modules, err := ReactorModules(build.Folder)
+		if err != nil {
+			return nil, err
+		}
+		files, err := ChangedFiles(build.Folder, base)
+		if err != nil {
+			return nil, err
+		}
+		changed, all := ChangedModules(modules, files)
+		if all {
+			return nil, nil
+		}
+		if len(changed) == 0 {
+			return &Reactor{Unchanged: true}, nil
+		}
+		reactor.Modules = changed
+	}
+	if len(reactor.Modules) == 0 {
+		if reactor.AlsoMake || reactor.AlsoMakeDependents {
+			return nil, fmt.Errorf("also_make and also_make_dependents require modules")
+		}
+		return nil, nil
+	}
+	return reactor, nil
+}
+
+// Args renders the selection as mvn arguments.
+func (r *Reactor) Args() []string {
+	if len(r.Modules) == 0 {
+		return nil
+	}
+	args := []string{"--projects", strings.Join(r.Modules, ",")}
+	if r.AlsoMake {
+		args = append(args, "--also-make")
+	}
+	if r.AlsoMakeDependents {
+		args = append(args, "--also-make-dependents")
+	}
+	return args
+}
+
+// ReactorModules returns the folders of all modules below the pom of folder,
+// relative to folder and following nested aggregators.
+func ReactorModules(folder string) ([]string, error) {
+	var modules []string
+	seen := map[string]bool{}
+	var walk func(dir string) error
+	walk = func(dir string) error {
+		pom, err := parsePom(filepath.Join(folder, dir, PomFile))
+		if err != nil {
+			return err
+		}
+		for _, module := range pom.Modules {
+			module = strings.TrimSpace(module)
+			if strings.HasSuffix(module, ".xml") {
+				module = path.Dir(module)
+			}
+			module = path.Join(dir, module)
+			if seen[module] || strings.HasPrefix(module, "..") {
+				continue
+			}
+			seen[module] = true
+			modules = append(modules, module)
+			if err := walk(module); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := walk("."); err != nil {
+		return nil, err
+	}
+	sort.Strings(modules)
+	return modules, nil
+}
+
+// ChangedFiles lists the files below folder changed since the merge base of
+// base and HEAD, uncommitted changes included, relative to folder.
+func ChangedFiles(folder, base string) ([]string, error) {
+	if strings.HasPrefix(base, "-") {
+		return nil, fmt.Errorf("invalid changed_since %q", base)
+	}
+	mergeBase, err := git(folder, "merge-base", base, "HEAD")
+	if err != nil {
+		return nil, err
+	}
+	out, err := git(folder, "diff", "--name-only", "-z", "--relative", strings.TrimSpace(mergeBase), "--")
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, file := range strings.Split(out, "\x00") {
+		if file != "" {
+			files = append(files, file)
+		}
+	}
+	return files, nil
+}
+
+func git(folder string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = folder
+	out, err := cmd.Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return "", fmt.Errorf("git %s: %s", strings.Join(args, " "), strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return "", err
+	}
+	return string(out), nil
+}
+
+// ChangedModules maps the changed files to the innermost module containing
+// them. all is set when a build input of the root project changed, which
+// affects every module. Other files outside the modules, like docs, are ignored.
+func ChangedModules(modules, files []string) (changed []string, all bool) {
+	selected := map[string]bool{}
+	for _, file := range files {
+		owner := ""
+		for _, module := range modules {
+			if strings.HasPrefix(file, module+"/") && len(module) > len(owner) {
+				owner = module
+			}
+		}
+		if owner != "" {
+			selected[owner] = true
+			continue
+		}
+		if file == PomFile || strings.HasPrefix(file, WrapperScript) ||
+			strings.HasPrefix(file, ".mvn/") || strings.HasPrefix(file, "src/") {
+			return nil, true
+		}
+	}
+	for module := range selected {
+		changed = append(changed, module)
+	}
+	sort.Strings(changed)
+	return changed, false
+}
@@ -0,0 +1,149 @@
+package maven
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/containifyci/engine-ci/pkg/container"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeReactor(t *testing.T) string {
+	folder := t.TempDir()
+	writePom(t, folder, `<project><modules><module>core</module><module>services</module><module>web/pom.xml</module></modules></project>`)
+	writePom(t, filepath.Join(folder, "core"), `<project/>`)
+	writePom(t, filepath.Join(folder, "services"), `<project><modules><module>billing</module><module>orders</module></modules></project>`)
+	writePom(t, filepath.Join(folder, "services/billing"), `<project/>`)
+	writePom(t, filepath.Join(folder, "services/orders"), `<project/>`)
+	writePom(t, filepath.Join(folder, "web"), `<project/>`)
+	return folder
+}
+
+func runGit(t *testing.T, folder string, args ...string) {
+	cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+	cmd.Dir = folder
+	out, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(out))
+}
+
+func writeFile(t *testing.T, file, content string) {
+	require.NoError(t, os.MkdirAll(filepath.Dir(file), 0o755))
+	require.NoError(t, os.WriteFile(file, []byte(content), 0o644))
+}
+
+func TestReactorModules(t *testing.T) {
+	modules, err := ReactorModules(writeReactor(t))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"core", "services", "services/billing", "services/orders", "web"}, modules)
+}
+
+func TestChangedModules(t *testing.T) {
+	modules := []string{"core", "services", "services/billing", "services/orders", "web"}
+
+	changed, all := ChangedModules(modules, []string{"services/billing/src/main/java/A.java", "core/pom.xml", "services/billing/pom.xml", "README.md"})
+	assert.False(t, all)
+	assert.Equal(t, []string{"core", "services/billing"}, changed)
+
+	changed, all = ChangedModules(modules, []string{"docs/index.md"})
+	assert.False(t, all)
+	assert.Empty(t, changed)
+
+	for _, file := range []string{"pom.xml", ".mvn/maven.config", "mvnw", "src/main/java/Root.java"} {
+		_, all = ChangedModules(modules, []string{"web/src/A.java", file})
+		assert.True(t, all, file)
+	}
+}
+
+func TestReactorArgs(t *testing.T) {
+	reactor, err := GetReactor(container.Build{Custom: container.Custom{}})
+	require.NoError(t, err)
+	assert.Nil(t, reactor)
+
+	reactor, err = GetReactor(container.Build{Custom: container.Custom{"modules": {"core, :web"}, "also_make": {"true"}}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"--projects", "core,:web", "--also-make"}, reactor.Args())
+
+	_, err = GetReactor(container.Build{Custom: container.Custom{"also_make_dependents": {"true"}}})
+	assert.Error(t, err)
+	_, err = GetReactor(container.Build{Custom: container.Custom{"modules": {"core"}, "changed_since": {"main"}}})
+	assert.Error(t, err)
+}
+
+func TestReactorChangedSince(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not available")
+	}
+	folder := writeReactor(t)
+	runGit(t, folder, "init", "-q", "-b", "main")
+	runGit(t, folder, "add", "-A")
+	runGit(t, folder, "commit", "-q", "-m", "initial")
+	runGit(t, folder, "checkout", "-q", "-b", "feature")
+
+	build := container.Build{Folder: folder, Custom: container.Custom{"changed_since": {"main"}}}
+	reactor, err := GetReactor(build)
+	require.NoError(t, err)
+	assert.Equal(t, &Reactor{Unchanged: true}, reactor)
+
+	writeFile(t, filepath.Join(folder, "services/orders/src/main/java/Order Service.java"), "class A {}")
+	runGit(t, folder, "add", "-A")
+	runGit(t, folder, "commit", "-q", "-m", "orders")
+	// uncommitted changes count as well
+	writeFile(t, filepath.Join(folder, "core/pom.xml"), "<project><!-- changed --></project>")
+
+	reactor, err = GetReactor(build)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"--projects", "core,services/orders", "--also-make", "--also-make-dependents"}, reactor.Args())
+
+	build.Custom["also_make"] = []string{"false"}
+	reactor, err = GetReactor(build)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"--projects", "core,services/orders", "--also-make-dependents"}, reactor.Args())
+
+	writeFile(t, filepath.Join(folder, "pom.xml"), "<project><modules><module>core</module></modules></project>")
+	reactor, err = GetReactor(build)
+	require.NoError(t, err)
+	assert.Nil(t, reactor)
+
+	_, err = GetReactor(container.Build{Folder: folder, Custom: container.Custom{"changed_since": {"unknown-ref"}}})
+	assert.ErrorContains(t, err, "git merge-base")
+	_, err = GetReactor(container.Build{Folder: folder, Custom: container.Custom{"changed_since": {"--output=/tmp/x"}}})
+	assert.ErrorContains(t, err, "invalid changed_since")
+}
+
+func TestUnchangedReactorSkipsShipping(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not available")
+	}
+	folder := writeReactor(t)
+	runGit(t, folder, "init", "-q", "-b", "main")
+	runGit(t, folder, "add", "-A")
+	runGit(t, folder, "commit", "-q", "-m", "initial")
+
+	arg := InitTest(t)
+	arg.Folder = folder
+	arg.Custom["changed_since"] = []string{"main"}
+	arg.Custom["publish"] = []string{"true"}
+	engine := useFakeEngine(t, "tomcat:latest")
+
+	image, err := NewProd().RunWithBuild(*arg)
+	require.NoError(t, err)
+	assert.Empty(t, image)
+	assert.NotContains(t, engine.Images, "index.docker.io/library/test-image:latest")
+
+	id, err := NewPublish().RunWithBuild(*arg)
+	require.NoError(t, err)
+	assert.Empty(t, id)
+}
+
+func TestBuildScriptWithReactor(t *testing.T) {
+	arg := InitTest(t)
+	arg.Custom["modules"] = []string{"core"}
+	arg.Custom["also_make"] = []string{"true"}
+	arg.Custom["profiles"] = []string{"ci"}
+
+	mc := new(arg)
+	assert.Equal(t, "#!/bin/sh\nset -xe\ncd .\nmvn --batch-mode --threads 2 package --projects core --also-make -Pci\n", mc.BuildScript())
+}
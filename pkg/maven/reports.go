@@ -0,0 +1,68 @@
+package maven
+
+import (
+	"io/fs"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// TargetFile is a report a module of the reactor wrote below its target/.
+type TargetFile struct {
+	// Module is the module folder relative to the project folder, "." for the root.
+	Module string
+	// Name is the path of the report below target/.
+	Name string
+	Path string
+}
+
+// findTargetFiles walks the modules below folder for reports matching one of
+// patterns below target/, e.g. surefire-reports/TEST-*.xml. Reports older than
+// since are left over from previous builds and skipped, a zero since finds all.
+func findTargetFiles(folder string, since time.Time, patterns ...string) ([]TargetFile, error) {
+	var files []TargetFile
+	err := filepath.WalkDir(folder, func(file string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if name := d.Name(); file != folder && (strings.HasPrefix(name, ".") || name == "node_modules" || name == "src") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, err := filepath.Rel(folder, file)
+		if err != nil {
+			return err
+		}
+		module, name, ok := cutTarget(filepath.ToSlash(rel))
+		if !ok || !matchesAny(name, patterns) {
+			return nil
+		}
+		if info, err := d.Info(); err != nil || info.ModTime().Before(since) {
+			return err
+		}
+		files = append(files, TargetFile{Module: module, Name: name, Path: file})
+		return nil
+	})
+	return files, err
+}
+
+// cutTarget splits a path at the target/ folder of its module.
+func cutTarget(rel string) (module, name string, ok bool) {
+	if name, ok := strings.CutPrefix(rel, "target/"); ok {
+		return ".", name, true
+	}
+	module, name, ok = strings.Cut(rel, "/target/")
+	return module, name, ok
+}
+
+func matchesAny(name string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
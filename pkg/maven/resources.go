@@ -0,0 +1,101 @@
+package maven
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/containifyci/engine-ci/pkg/container"
+)
+
+const (
+	DEFAULT_MEMORY          = int64(4073741824)
+	DEFAULT_CPU             = uint64(2048)
+	DEFAULT_HEAP_PERCENTAGE = 25
+
+	// cpuShares are the CPU shares of one CPU.
+	cpuShares = 1024
+)
+
+// threadCount matches the values of mvn --threads, a number of threads or a
+// multiple of the CPU cores like 1C.
+var threadCount = regexp.MustCompile(`^([1-9][0-9]*|([0-9]*\.)?[0-9]+C)$`)
+
+// Resources are the limits of the build container and the JVM options of Maven.
+type Resources struct {
+	Memory    int64
+	CPU       uint64
+	MavenOpts string
+	// Threads is the --threads value of mvn.
+	Threads string
+}
+
+// GetResources reads the memory, cpu, threads, heap_percentage and maven_opts
+// properties. Unless maven_opts is set the Maven heap is heap_percentage of
+// the memory limit, so the JVM always fits into the container. Unless threads
+// is set the reactor is built with a thread per CPU the cpu shares grant.
+func GetResources(build container.Build) (Resources, error) {
+	res := Resources{Memory: DEFAULT_MEMORY, CPU: DEFAULT_CPU}
+
+	if v := build.Custom.String("memory"); v != "" {
+		memory, err := ParseMemory(v)
+		if err != nil {
+			return res, err
+		}
+		res.Memory = memory
+	}
+	if v := build.Custom.String("cpu"); v != "" {
+		cpu, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return res, fmt.Errorf("invalid cpu %q: %w", v, err)
+		}
+		res.CPU = cpu
+	}
+	res.Threads = strconv.FormatUint(max(res.CPU/cpuShares, 1), 10)
+	if v := build.Custom.String("threads"); v != "" {
+		if !threadCount.MatchString(v) {
+			return res, fmt.Errorf("invalid threads %q", v)
+		}
+		res.Threads = v
+	}
+
+	if opts := build.Custom["maven_opts"]; len(opts) > 0 {
+		res.MavenOpts = strings.Join(opts, " ")
+		return res, nil
+	}
+	percentage := DEFAULT_HEAP_PERCENTAGE
+	if v := build.Custom.String("heap_percentage"); v != "" {
+		p, err := strconv.Atoi(v)
+		if err != nil || p <= 0 || p > 100 {
+			return res, fmt.Errorf("invalid heap_percentage %q", v)
+		}
+		percentage = p
+	}
+	heap := res.Memory * int64(percentage) / 100 / (1 << 20)
+	res.MavenOpts = fmt.Sprintf("-Xms%dm -Xmx%dm -XX:MaxDirectMemorySize=%dm", heap, heap, heap)
+	return res, nil
+}
+
+// ParseMemory parses a byte count with an optional binary k, m or g suffix, e.g. 4g.
+func ParseMemory(value string) (int64, error) {
+	v := strings.ToLower(strings.TrimSpace(value))
+	v = strings.TrimSuffix(v, "b")
+	multiplier := int64(1)
+	switch {
+	case strings.HasSuffix(v, "k"):
+		multiplier = 1 << 10
+	case strings.HasSuffix(v, "m"):
+		multiplier = 1 << 20
+	case strings.HasSuffix(v, "g"):
+		multiplier = 1 << 30
+	}
+	if multiplier > 1 {
+		v = v[:len(v)-1]
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid memory %q", value)
+	}
+	return n * multiplier, nil
+}
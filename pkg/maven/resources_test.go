@@ -0,0 +1,80 @@
+package maven
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMemory(t *testing.T) {
+	tests := map[string]int64{
+		"1073741824": 1 << 30,
+		"512m":       512 << 20,
+		"8G":         8 << 30,
+		"2gb":        2 << 30,
+		"64k":        64 << 10,
+	}
+	for value, expected := range tests {
+		memory, err := ParseMemory(value)
+		assert.NoError(t, err, value)
+		assert.Equal(t, expected, memory, value)
+	}
+
+	for _, value := range []string{"", "lots", "-1g", "1.5g"} {
+		_, err := ParseMemory(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestGetResources(t *testing.T) {
+	build := InitTest(t)
+
+	res, err := GetResources(*build)
+	require.NoError(t, err)
+	assert.Equal(t, Resources{Memory: 4073741824, CPU: 2048, MavenOpts: "-Xms971m -Xmx971m -XX:MaxDirectMemorySize=971m", Threads: "2"}, res)
+
+	build.Custom["memory"] = []string{"8g"}
+	build.Custom["cpu"] = []string{"4096"}
+	build.Custom["heap_percentage"] = []string{"50"}
+	res, err = GetResources(*build)
+	require.NoError(t, err)
+	assert.Equal(t, Resources{Memory: 8 << 30, CPU: 4096, MavenOpts: "-Xms4096m -Xmx4096m -XX:MaxDirectMemorySize=4096m", Threads: "4"}, res)
+
+	build.Custom["maven_opts"] = []string{"-Xmx2g", "-XX:+UseParallelGC"}
+	res, err = GetResources(*build)
+	require.NoError(t, err)
+	assert.Equal(t, "-Xmx2g -XX:+UseParallelGC", res.MavenOpts)
+
+	build.Custom["heap_percentage"] = []string{"150"}
+	delete(build.Custom, "maven_opts")
+	_, err = GetResources(*build)
+	assert.Error(t, err)
+}
+
+func TestGetResourcesThreads(t *testing.T) {
+	build := InitTest(t)
+
+	build.Custom["cpu"] = []string{"512"}
+	res, err := GetResources(*build)
+	require.NoError(t, err)
+	assert.Equal(t, "1", res.Threads)
+
+	build.Custom["cpu"] = []string{"3000"}
+	res, err = GetResources(*build)
+	require.NoError(t, err)
+	assert.Equal(t, "2", res.Threads)
+
+	for _, threads := range []string{"8", "1C", "1.5C", ".5C"} {
+		build.Custom["threads"] = []string{threads}
+		res, err = GetResources(*build)
+		require.NoError(t, err, threads)
+		assert.Equal(t, threads, res.Threads)
+	}
+
+	for _, threads := range []string{"0", "-1", "C", "2 -X", "1.5"} {
+		build.Custom["threads"] = []string{threads}
+		_, err = GetResources(*build)
+		assert.Error(t, err, threads)
+	}
+}
@@ -0,0 +1,124 @@
+package maven
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/containifyci/engine-ci/pkg/cri/utils"
+	"github.com/docker/docker/api/types"
+	dockercontainer "github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/google/go-containerregistry/pkg/v1/daemon"
+)
+
+// Runtime is the part of the engine API used next to the engine-ci container.
+type Runtime interface {
+	daemon.Client
+	ContainerInspect(ctx context.Context, id string) (dockercontainer.InspectResponse, error)
+	ContainerLogs(ctx context.Context, id string, options dockercontainer.LogsOptions) (io.ReadCloser, error)
+	NetworkDisconnect(ctx context.Context, network, id string, force bool) error
+	ContainerExecCreate(ctx context.Context, id string, options dockercontainer.ExecOptions) (dockercontainer.ExecCreateResponse, error)
+	ContainerExecAttach(ctx context.Context, execID string, options dockercontainer.ExecAttachOptions) (types.HijackedResponse, error)
+	ContainerExecInspect(ctx context.Context, execID string) (dockercontainer.ExecInspect, error)
+	ContainerStop(ctx context.Context, id string, options dockercontainer.StopOptions) error
+	ContainerRemove(ctx context.Context, id string, options dockercontainer.RemoveOptions) error
+	Close() error
+}
+
+// runtimeClient is replaced by the tests with a fake runtime.
+var runtimeClient = func(runtime utils.RuntimeType) (Runtime, error) {
+	return RuntimeClient(runtime)
+}
+
+// RuntimeClient connects to the engine API of the container runtime the build
+// runs on. Docker and podman both serve it, podman on its own socket. It covers
+// what the engine-ci container does not expose, like loading images, exec and
+// the networks of a container, for the containers engine-ci created.
+func RuntimeClient(runtime utils.RuntimeType) (*client.Client, error) {
+	opts := []client.Opt{client.FromEnv, client.WithAPIVersionNegotiation()}
+	if host := RuntimeHost(runtime); host != "" {
+		opts = append(opts, client.WithHost(host))
+	}
+	return client.NewClientWithOpts(opts...)
+}
+
+// RuntimeHost returns the podman API socket, CONTAINER_HOST or the socket of
+// the rootless service when it exists. Docker is reached through DOCKER_HOST or
+// its default socket, so RuntimeHost is empty for it.
+func RuntimeHost(runtime utils.RuntimeType) string {
+	if runtime != utils.Podman {
+		return ""
+	}
+	if host := os.Getenv("CONTAINER_HOST"); host != "" {
+		return host
+	}
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		socket := filepath.Join(dir, "podman", "podman.sock")
+		if _, err := os.Stat(socket); err == nil {
+			return "unix://" + socket
+		}
+	}
+	return "unix:///run/podman/podman.sock"
+}
+
+// ContainerAddress returns the IP address of the container id, other
+// containers of the runtime reach it there.
+func ContainerAddress(cli Runtime, id string) (string, error) {
+	inspect, err := cli.ContainerInspect(context.Background(), id)
+	if err != nil {
+		return "", err
+	}
+	if inspect.NetworkSettings != nil {
+		for _, network := range inspect.NetworkSettings.Networks {
+			if network != nil && network.IPAddress != "" {
+				return network.IPAddress, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("container %s has no network address", id)
+}
+
+// ContainerLogs copies the output of the container id to w.
+func ContainerLogs(cli Runtime, id string, w io.Writer) error {
+	logs, err := cli.ContainerLogs(context.Background(), id, dockercontainer.LogsOptions{ShowStdout: true, ShowStderr: true})
+	if err != nil {
+		return err
+	}
+	defer logs.Close()
+	_, err = stdcopy.StdCopy(w, w, logs)
+	return err
+}
+
+// DisconnectNetworks detaches the created container id from all its networks,
+// so once started it only has the loopback interface. It fails when the
+// container shares the network of the host or is still connected afterwards.
+func DisconnectNetworks(cli Runtime, id string) error {
+	ctx := context.Background()
+	inspect, err := cli.ContainerInspect(ctx, id)
+	if err != nil {
+		return err
+	}
+	if inspect.ContainerJSONBase != nil && inspect.HostConfig != nil && inspect.HostConfig.NetworkMode.IsHost() {
+		return fmt.Errorf("container %s uses the network of the host", id)
+	}
+	if inspect.NetworkSettings != nil {
+		for network := range inspect.NetworkSettings.Networks {
+			if err := cli.NetworkDisconnect(ctx, network, id, true); err != nil {
+				return fmt.Errorf("failed to disconnect container %s from network %s: %w", id, network, err)
+			}
+		}
+	}
+
+	inspect, err = cli.ContainerInspect(ctx, id)
+	if err != nil {
+		return err
+	}
+	if inspect.NetworkSettings != nil && len(inspect.NetworkSettings.Networks) > 0 {
+		return fmt.Errorf("container %s is still connected to a network", id)
+	}
+	return nil
+}
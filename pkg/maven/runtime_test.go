@@ -0,0 +1,274 @@
+package maven
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/containifyci/engine-ci/pkg/cri/utils"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+	dockerspec "github.com/moby/docker-image-spec/specs-go/v1"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeEngine is a container runtime keeping its images in memory.
+type fakeEngine struct {
+	Images map[string]v1.Image
+	// Address is the IP address of every container.
+	Address string
+	Logs    string
+	// Disconnected lists the containers without network.
+	Disconnected map[string]bool
+	// NetworkErr fails every network disconnect.
+	NetworkErr error
+	// Running tracks the state of the containers when set, others do not exist.
+	Running map[string]bool
+	Stopped []string
+	Removed []string
+	// Execs records the exec calls, Stdin what they were sent.
+	Execs    []container.ExecOptions
+	Stdin    bytes.Buffer
+	ExitCode int
+}
+
+// fakeConn is the hijacked connection of an exec, it records the input.
+type fakeConn struct {
+	net.Conn
+	stdin *bytes.Buffer
+}
+
+func (c *fakeConn) Write(p []byte) (int, error) { return c.stdin.Write(p) }
+
+func (c *fakeConn) CloseWrite() error { return nil }
+
+func (c *fakeConn) Close() error { return nil }
+
+// useFakeEngine makes the runtime client return a fake engine holding a
+// random base image under each of bases.
+func useFakeEngine(t *testing.T, bases ...string) *fakeEngine {
+	t.Helper()
+	runtime := &fakeEngine{Images: map[string]v1.Image{}, Address: "10.88.0.7", Disconnected: map[string]bool{}}
+	for _, base := range bases {
+		img, err := random.Image(64, 1)
+		require.NoError(t, err)
+		ref, err := name.ParseReference(base)
+		require.NoError(t, err)
+		runtime.Images[ref.Name()] = img
+	}
+	previous := runtimeClient
+	runtimeClient = func(utils.RuntimeType) (Runtime, error) { return runtime, nil }
+	t.Cleanup(func() { runtimeClient = previous })
+	return runtime
+}
+
+func (f *fakeEngine) image(ref string) (v1.Image, error) {
+	if img, ok := f.Images[ref]; ok {
+		return img, nil
+	}
+	if parsed, err := name.ParseReference(ref); err == nil {
+		if img, ok := f.Images[parsed.Name()]; ok {
+			return img, nil
+		}
+	}
+	for _, img := range f.Images {
+		if id, err := img.ConfigName(); err == nil && id.String() == ref {
+			return img, nil
+		}
+	}
+	return nil, fmt.Errorf("no such image: %s", ref)
+}
+
+func (f *fakeEngine) NegotiateAPIVersion(context.Context) {}
+
+func (f *fakeEngine) Close() error { return nil }
+
+func (f *fakeEngine) ImageSave(_ context.Context, refs []string, _ ...client.ImageSaveOption) (io.ReadCloser, error) {
+	img, err := f.image(refs[0])
+	if err != nil {
+		return nil, err
+	}
+	tag, err := name.NewTag(refs[0])
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := tarball.Write(tag, img, &buf); err != nil {
+		return nil, err
+	}
+	return io.NopCloser(&buf), nil
+}
+
+func (f *fakeEngine) ImageLoad(_ context.Context, r io.Reader, _ ...client.ImageLoadOption) (image.LoadResponse, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return image.LoadResponse{}, err
+	}
+	opener := func() (io.ReadCloser, error) { return io.NopCloser(bytes.NewReader(data)), nil }
+	manifest, err := tarball.LoadManifest(opener)
+	if err != nil {
+		return image.LoadResponse{}, err
+	}
+	for _, descriptor := range manifest {
+		for _, repoTag := range descriptor.RepoTags {
+			tag, err := name.NewTag(repoTag)
+			if err != nil {
+				return image.LoadResponse{}, err
+			}
+			img, err := tarball.Image(opener, &tag)
+			if err != nil {
+				return image.LoadResponse{}, err
+			}
+			f.Images[tag.Name()] = img
+		}
+	}
+	return image.LoadResponse{Body: io.NopCloser(strings.NewReader(`{"stream":"Loaded image"}`))}, nil
+}
+
+func (f *fakeEngine) ImageTag(_ context.Context, source, target string) error {
+	img, err := f.image(source)
+	if err != nil {
+		return err
+	}
+	f.Images[target] = img
+	return nil
+}
+
+func (f *fakeEngine) ImageInspectWithRaw(_ context.Context, ref string) (image.InspectResponse, []byte, error) {
+	img, err := f.image(ref)
+	if err != nil {
+		return image.InspectResponse{}, nil, err
+	}
+	id, err := img.ConfigName()
+	if err != nil {
+		return image.InspectResponse{}, nil, err
+	}
+	cfg, err := img.ConfigFile()
+	if err != nil {
+		return image.InspectResponse{}, nil, err
+	}
+	inspect := image.InspectResponse{
+		ID:           id.String(),
+		Created:      cfg.Created.Format(time.RFC3339Nano),
+		Architecture: cfg.Architecture,
+		Os:           cfg.OS,
+		RootFS:       image.RootFS{Type: cfg.RootFS.Type},
+		Config: &dockerspec.DockerOCIImageConfig{
+			ImageConfig: ocispec.ImageConfig{
+				Env:        cfg.Config.Env,
+				Entrypoint: cfg.Config.Entrypoint,
+				Cmd:        cfg.Config.Cmd,
+				WorkingDir: cfg.Config.WorkingDir,
+			},
+		},
+	}
+	for _, diffID := range cfg.RootFS.DiffIDs {
+		inspect.RootFS.Layers = append(inspect.RootFS.Layers, diffID.String())
+	}
+	return inspect, nil, nil
+}
+
+func (f *fakeEngine) ImageHistory(context.Context, string, ...client.ImageHistoryOption) ([]image.HistoryResponseItem, error) {
+	return nil, nil
+}
+
+func (f *fakeEngine) ContainerInspect(_ context.Context, id string) (container.InspectResponse, error) {
+	running, ok := f.Running[id]
+	if f.Running != nil && !ok {
+		return container.InspectResponse{}, fmt.Errorf("no such container: %s", id)
+	}
+	inspect := container.InspectResponse{
+		ContainerJSONBase: &container.ContainerJSONBase{ID: id, State: &container.State{Running: running}},
+		NetworkSettings:   &container.NetworkSettings{Networks: map[string]*network.EndpointSettings{}},
+	}
+	if f.Address != "" && !f.Disconnected[id] {
+		inspect.NetworkSettings.Networks["bridge"] = &network.EndpointSettings{IPAddress: f.Address}
+	}
+	return inspect, nil
+}
+
+func (f *fakeEngine) NetworkDisconnect(_ context.Context, network, id string, _ bool) error {
+	if f.NetworkErr != nil {
+		return f.NetworkErr
+	}
+	if network != "bridge" {
+		return fmt.Errorf("no such network: %s", network)
+	}
+	f.Disconnected[id] = true
+	return nil
+}
+
+func (f *fakeEngine) ContainerLogs(context.Context, string, container.LogsOptions) (io.ReadCloser, error) {
+	var buf bytes.Buffer
+	_, err := stdcopy.NewStdWriter(&buf, stdcopy.Stdout).Write([]byte(f.Logs))
+	return io.NopCloser(&buf), err
+}
+
+func (f *fakeEngine) ContainerExecCreate(_ context.Context, id string, options container.ExecOptions) (container.ExecCreateResponse, error) {
+	f.Execs = append(f.Execs, options)
+	return container.ExecCreateResponse{ID: fmt.Sprintf("exec-%d", len(f.Execs))}, nil
+}
+
+func (f *fakeEngine) ContainerExecAttach(context.Context, string, container.ExecAttachOptions) (types.HijackedResponse, error) {
+	var buf bytes.Buffer
+	_, err := stdcopy.NewStdWriter(&buf, stdcopy.Stdout).Write([]byte(f.Logs))
+	return types.HijackedResponse{Conn: &fakeConn{stdin: &f.Stdin}, Reader: bufio.NewReader(&buf)}, err
+}
+
+func (f *fakeEngine) ContainerExecInspect(context.Context, string) (container.ExecInspect, error) {
+	return container.ExecInspect{ExitCode: f.ExitCode}, nil
+}
+
+func (f *fakeEngine) ContainerStop(_ context.Context, id string, _ container.StopOptions) error {
+	f.Stopped = append(f.Stopped, id)
+	f.Running[id] = false
+	return nil
+}
+
+func (f *fakeEngine) ContainerRemove(_ context.Context, id string, _ container.RemoveOptions) error {
+	f.Removed = append(f.Removed, id)
+	delete(f.Running, id)
+	return nil
+}
+
+func TestContainerLogs(t *testing.T) {
+	engine := &fakeEngine{Logs: "started\n"}
+	var out bytes.Buffer
+	require.NoError(t, ContainerLogs(engine, "app", &out))
+	assert.Equal(t, "started\n", out.String())
+}
+
+func TestDisconnectNetworks(t *testing.T) {
+	engine := &fakeEngine{Address: "10.88.0.7", Disconnected: map[string]bool{}}
+	require.NoError(t, DisconnectNetworks(engine, "build"))
+	assert.True(t, engine.Disconnected["build"])
+
+	_, err := ContainerAddress(engine, "build")
+	assert.ErrorContains(t, err, "has no network address")
+}
+
+func TestRuntimeHost(t *testing.T) {
+	t.Setenv("CONTAINER_HOST", "")
+	t.Setenv("XDG_RUNTIME_DIR", "")
+	assert.Equal(t, "", RuntimeHost(utils.Docker))
+	assert.Equal(t, "unix:///run/podman/podman.sock", RuntimeHost(utils.Podman))
+
+	t.Setenv("CONTAINER_HOST", "unix:///tmp/podman.sock")
+	assert.Equal(t, "unix:///tmp/podman.sock", RuntimeHost(utils.Podman))
+}
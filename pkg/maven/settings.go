@@ -0,0 +1,297 @@
+package maven
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/containifyci/engine-ci/pkg/container"
+)
+
+// MirrorID is the id of the mirror configured by maven_mirror_url, its
+// credentials are read from maven_username.mirror and maven_password.mirror.
+const MirrorID = "mirror"
+
+// SettingsFile is the generated settings.xml inside the build container. It
+// lives outside the cache mount so nothing of it reaches the host.
+const SettingsFile = "/tmp/containifyci/settings.xml"
+
+// Server holds the credentials of a repository or mirror. Username and
+// Password are references, env:NAME reads the host environment variable NAME
+// and anything else is taken literally.
+type Server struct {
+	ID       string
+	Username string
+	Password string
+}
+
+type Repository struct {
+	ID  string
+	URL string
+}
+
+type Mirror struct {
+	ID       string
+	URL      string
+	MirrorOf string
+}
+
+// Settings are the repositories, mirrors and credentials declared in the
+// Custom properties. The generated settings.xml refers to the credentials as
+// ${env.NAME} and the values are handed to the build container by Env, so
+// no secret is ever written to a file.
+type Settings struct {
+	Servers      []Server
+	Repositories []Repository
+	Mirrors      []Mirror
+}
+
+// GetSettings reads maven_repository.<id> and maven_mirror.<id> urls, the
+// maven_mirror_of.<id> patterns (* by default) and the maven_username.<id>
+// and maven_password.<id> credentials. It returns nil when nothing is declared.
+func GetSettings(build container.Build) (*Settings, error) {
+	servers := map[string]*Server{}
+	settings := &Settings{}
+	for _, key := range sortedKeys(build.Custom) {
+		kind, id, ok := strings.Cut(key, ".")
+		if !ok || id == "" {
+			continue
+		}
+		value := build.Custom.String(key)
+		switch kind {
+		case "maven_repository", "maven_mirror":
+			if err := validateRepositoryURL(value); err != nil {
+				return nil, fmt.Errorf("invalid %s: %w", key, err)
+			}
+			if kind == "maven_repository" {
+				settings.Repositories = append(settings.Repositories, Repository{ID: id, URL: value})
+				continue
+			}
+			mirrorOf := build.Custom.String("maven_mirror_of." + id)
+			if mirrorOf == "" {
+				mirrorOf = "*"
+			}
+			settings.Mirrors = append(settings.Mirrors, Mirror{ID: id, URL: value, MirrorOf: mirrorOf})
+		case "maven_username", "maven_password":
+			server, ok := servers[id]
+			if !ok {
+				server = &Server{ID: id}
+				servers[id] = server
+			}
+			if kind == "maven_username" {
+				server.Username = value
+			} else {
+				server.Password = value
+			}
+		}
+	}
+	mirror, err := GetMirrorURL(build)
+	if err != nil {
+		return nil, err
+	}
+	if mirror != "" {
+		// listed last, mirrors declared for single repositories take precedence
+		settings.Mirrors = append(settings.Mirrors, Mirror{ID: MirrorID, URL: mirror, MirrorOf: "*"})
+	}
+	// the credentials of two ids must not end up in the same variables
+	envIDs := map[string]string{}
+	for _, id := range sortedKeys(servers) {
+		name := credentialEnv(id, "")
+		if other, ok := envIDs[name]; ok {
+			return nil, fmt.Errorf("credentials of %s and %s both map to the environment variables %s*, rename one of the ids", other, id, name)
+		}
+		envIDs[name] = id
+		settings.Servers = append(settings.Servers, *servers[id])
+	}
+	if len(settings.Servers) == 0 && len(settings.Repositories) == 0 && len(settings.Mirrors) == 0 {
+		return nil, nil
+	}
+	return settings, nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func validateRepositoryURL(value string) error {
+	u, err := url.Parse(value)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" && u.Scheme != "file" {
+		return fmt.Errorf("unsupported url %q", value)
+	}
+	if u.User != nil {
+		return fmt.Errorf("url %q must not contain credentials, use maven_username and maven_password", u.Redacted())
+	}
+	return nil
+}
+
+// GetMirrorURL returns the maven_mirror_url all downloads are routed through,
+// without trailing slash. The url ends up in the builder Dockerfile and
+// scripts, so only characters the shell treats literally are accepted.
+func GetMirrorURL(build container.Build) (string, error) {
+	mirror := strings.TrimSuffix(build.Custom.String("maven_mirror_url"), "/")
+	if mirror == "" {
+		return "", nil
+	}
+	if err := validateRepositoryURL(mirror); err != nil {
+		return "", fmt.Errorf("invalid maven_mirror_url: %w", err)
+	}
+	if !strings.HasPrefix(mirror, "http") || !safeShellArg.MatchString(mirror) {
+		return "", fmt.Errorf("invalid maven_mirror_url %q", mirror)
+	}
+	return mirror, nil
+}
+
+// credentialEnv is the container environment variable holding a credential of server id.
+func credentialEnv(id, field string) string {
+	name := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' {
+			return r - 'a' + 'A'
+		}
+		if (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, id)
+	return fmt.Sprintf("MAVEN_SERVER_%s_%s", name, field)
+}
+
+// ResolveSecret resolves an env:NAME reference from the host environment,
+// other values are returned as they are.
+func ResolveSecret(ref string) (string, error) {
+	name, ok := strings.CutPrefix(ref, "env:")
+	if !ok {
+		return ref, nil
+	}
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return "", fmt.Errorf("environment variable %s is not set", name)
+	}
+	return value, nil
+}
+
+// Env resolves the credentials into the environment of the build container.
+func (s *Settings) Env() ([]string, error) {
+	var env []string
+	for _, server := range s.Servers {
+		for field, ref := range map[string]string{"USERNAME": server.Username, "PASSWORD": server.Password} {
+			if ref == "" {
+				continue
+			}
+			value, err := ResolveSecret(ref)
+			if err != nil {
+				return nil, fmt.Errorf("failed to resolve credentials of %s: %w", server.ID, err)
+			}
+			env = append(env, credentialEnv(server.ID, field)+"="+value)
+		}
+	}
+	sort.Strings(env)
+	return env, nil
+}
+
+type settingsXML struct {
+	XMLName        xml.Name             `xml:"settings"`
+	Xmlns          string               `xml:"xmlns,attr"`
+	Servers        *serversXML          `xml:"servers"`
+	Mirrors        *mirrorsXML          `xml:"mirrors"`
+	Profiles       *settingsProfilesXML `xml:"profiles"`
+	ActiveProfiles *activeProfilesXML   `xml:"activeProfiles"`
+}
+
+// the wrapper elements are pointers so empty lists are left out
+type serversXML struct {
+	Servers []serverXML `xml:"server"`
+}
+
+type mirrorsXML struct {
+	Mirrors []mirrorXML `xml:"mirror"`
+}
+
+type settingsProfilesXML struct {
+	Profiles []settingsProfile `xml:"profile"`
+}
+
+type activeProfilesXML struct {
+	ActiveProfiles []string `xml:"activeProfile"`
+}
+
+type serverXML struct {
+	ID       string `xml:"id"`
+	Username string `xml:"username,omitempty"`
+	Password string `xml:"password,omitempty"`
+}
+
+type mirrorXML struct {
+	ID       string `xml:"id"`
+	URL      string `xml:"url"`
+	MirrorOf string `xml:"mirrorOf"`
+}
+
+type settingsProfile struct {
+	ID                 string          `xml:"id"`
+	Repositories       []repositoryXML `xml:"repositories>repository"`
+	PluginRepositories []repositoryXML `xml:"pluginRepositories>pluginRepository"`
+}
+
+type repositoryXML struct {
+	ID  string `xml:"id"`
+	URL string `xml:"url"`
+}
+
+// XML renders the settings.xml, the repositories are added by an always
+// active containifyci profile.
+func (s *Settings) XML() ([]byte, error) {
+	doc := settingsXML{Xmlns: "http://maven.apache.org/SETTINGS/1.2.0"}
+	if len(s.Servers) > 0 {
+		doc.Servers = &serversXML{}
+	}
+	for _, server := range s.Servers {
+		entry := serverXML{ID: server.ID}
+		if server.Username != "" {
+			entry.Username = "${env." + credentialEnv(server.ID, "USERNAME") + "}"
+		}
+		if server.Password != "" {
+			entry.Password = "${env." + credentialEnv(server.ID, "PASSWORD") + "}"
+		}
+		doc.Servers.Servers = append(doc.Servers.Servers, entry)
+	}
+	if len(s.Mirrors) > 0 {
+		doc.Mirrors = &mirrorsXML{}
+	}
+	for _, mirror := range s.Mirrors {
+		doc.Mirrors.Mirrors = append(doc.Mirrors.Mirrors, mirrorXML(mirror))
+	}
+	if len(s.Repositories) > 0 {
+		profile := settingsProfile{ID: "containifyci"}
+		for _, repo := range s.Repositories {
+			profile.Repositories = append(profile.Repositories, repositoryXML(repo))
+			profile.PluginRepositories = append(profile.PluginRepositories, repositoryXML(repo))
+		}
+		doc.Profiles = &settingsProfilesXML{Profiles: []settingsProfile{profile}}
+		doc.ActiveProfiles = &activeProfilesXML{ActiveProfiles: []string{profile.ID}}
+	}
+	data, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), append(data, '\n')...), nil
+}
+
+// Setup renders the shell lines writing the settings.xml in the container.
+func (s *Settings) Setup() string {
+	// the settings only hold strings, marshalling them cannot fail
+	data, _ := s.XML()
+	return fmt.Sprintf("mkdir -p %s\nprintf '%%s' %s > %s\n",
+		shellQuote(path.Dir(SettingsFile)), shellQuote(string(data)), shellQuote(SettingsFile))
+}
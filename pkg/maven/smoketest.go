@@ -0,0 +1,137 @@
+package maven
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/containifyci/engine-ci/pkg/container"
+	"github.com/containifyci/engine-ci/pkg/cri/types"
+)
+
+const (
+	DEFAULT_SMOKE_STATUS  = "200"
+	DEFAULT_SMOKE_TIMEOUT = 120
+)
+
+// SmokeTest probes the HTTP endpoint of the prod image before it is pushed.
+type SmokeTest struct {
+	Path    string
+	Port    string
+	Status  string
+	Body    string
+	Timeout int
+}
+
+// NewSmokeTest reads the smoke test from the smoke_path, smoke_status,
+// smoke_body and smoke_timeout properties. It returns nil when smoke_path is
+// not set, the smoke test is opt-in.
+func NewSmokeTest(build container.Build, spec *ProdSpec) (*SmokeTest, error) {
+	path := build.Custom.String("smoke_path")
+	if path == "" {
+		return nil, nil
+	}
+	if len(spec.Ports) == 0 {
+		return nil, fmt.Errorf("prod image exposes no port to smoke test")
+	}
+
+	test := &SmokeTest{
+		Path:    "/" + strings.TrimPrefix(path, "/"),
+		Port:    spec.Ports[0],
+		Status:  build.Custom.String("smoke_status"),
+		Body:    build.Custom.String("smoke_body"),
+		Timeout: DEFAULT_SMOKE_TIMEOUT,
+	}
+	if test.Status == "" {
+		test.Status = DEFAULT_SMOKE_STATUS
+	}
+	if timeout := build.Custom.String("smoke_timeout"); timeout != "" {
+		v, err := strconv.Atoi(timeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid smoke_timeout %q: %w", timeout, err)
+		}
+		test.Timeout = v
+	}
+	return test, nil
+}
+
+// URL is the address of the app in the container reachable at host.
+func (s *SmokeTest) URL(host string) string {
+	return fmt.Sprintf("http://%s:%s%s", host, s.Port, s.Path)
+}
+
+// Script polls the endpoint of the app at host until it answers with the
+// expected status and body or the timeout passes.
+func (s *SmokeTest) Script(host string) string {
+	body := "true"
+	if s.Body != "" {
+		body = fmt.Sprintf("grep -qF -- %s /tmp/smoke-body", shellQuote(s.Body))
+	}
+	url := shellQuote(s.URL(host))
+	return fmt.Sprintf(`#!/bin/sh
+probe() {
+  if command -v curl >/dev/null 2>&1; then
+    curl -s -o /tmp/smoke-body -w '%%{http_code}' %s
+  else
+    wget -q -S -O /tmp/smoke-body %s 2>&1 | awk '/HTTP\//{print $2}' | tail -1
+  fi
+}
+i=0
+while [ $i -lt %d ]; do
+  status=$(probe || true)
+  if [ "$status" = %s ] && %s; then
+    echo "smoke test passed: %s answered $status"
+    exit 0
+  fi
+  sleep 1
+  i=$((i+1))
+done
+echo "smoke test failed: %s answered '$status' within %ds, expected %s"
+cat /tmp/smoke-body 2>/dev/null
+exit 1
+`, url, url, s.Timeout, shellQuote(s.Status), body, s.Path, s.Path, s.Timeout, s.Status)
+}
+
+// Smoke starts the prod image loaded into the runtime with its own
+// configuration and probes it from a container of the ProbeImage. The logs
+// of the app are printed when the probe fails.
+func (c *MavenContainer) Smoke(cli Runtime, image string, test *SmokeTest) error {
+	app := container.New(*c.GetBuild())
+	err := app.Create(types.ContainerConfig{Image: image, Platform: types.AutoPlatform})
+	if err != nil {
+		return err
+	}
+	err = app.Start()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := app.Stop(); err != nil {
+			slog.Warn("Failed to stop smoke test container", "error", err)
+		}
+	}()
+
+	host, err := ContainerAddress(cli, app.ID)
+	if err != nil {
+		return err
+	}
+	opts := types.ContainerConfig{}
+	opts.Image = c.ProbeImage
+	if opts.Image == "" {
+		opts.Image = MavenImage(*c.GetBuild())
+	}
+	opts.Script = test.Script(host)
+
+	slog.Info("Smoke testing prod image", "image", image, "url", test.URL(host), "status", test.Status)
+	err = c.BuildingContainer(opts)
+	if err != nil {
+		slog.Error("Smoke test failed, the logs of the app follow", "url", test.URL(host), "error", err)
+		if logErr := ContainerLogs(cli, app.ID, os.Stdout); logErr != nil {
+			slog.Warn("Failed to read smoke test container logs", "error", logErr)
+		}
+		return fmt.Errorf("smoke test of %s failed: %w", test.URL(host), err)
+	}
+	return nil
+}
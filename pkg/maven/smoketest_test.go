@@ -0,0 +1,101 @@
+package maven
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os/exec"
+	"testing"
+
+	"github.com/containifyci/engine-ci/pkg/cri"
+	"github.com/containifyci/engine-ci/pkg/cri/critest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewSmokeTest(t *testing.T) {
+	build := InitTest(t)
+	spec := NewProdSpec("tomcat:latest")
+	spec.Ports = []string{"8080"}
+
+	test, err := NewSmokeTest(*build, spec)
+	require.NoError(t, err)
+	assert.Nil(t, test)
+
+	build.Custom["smoke_path"] = []string{"hello"}
+	build.Custom["smoke_body"] = []string{"Hello World"}
+	build.Custom["smoke_timeout"] = []string{"30"}
+	test, err = NewSmokeTest(*build, spec)
+	require.NoError(t, err)
+	assert.Equal(t, &SmokeTest{Path: "/hello", Port: "8080", Status: "200", Body: "Hello World", Timeout: 30}, test)
+	assert.Equal(t, "http://10.88.0.7:8080/hello", test.URL("10.88.0.7"))
+
+	build.Custom["smoke_timeout"] = []string{"soon"}
+	_, err = NewSmokeTest(*build, spec)
+	assert.Error(t, err)
+}
+
+// runSmokeScript runs the smoke script on the host against a local server
+// standing in for the app.
+func runSmokeScript(t *testing.T, status int, body string, test *SmokeTest) error {
+	if _, err := exec.LookPath("curl"); err != nil {
+		t.Skip("curl is required to run the smoke script")
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/hello" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(status)
+		fmt.Fprint(w, body)
+	}))
+	defer server.Close()
+	u, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	test.Port = u.Port()
+	cmd := exec.Command("sh", "-c", test.Script(u.Hostname()))
+	out, err := cmd.CombinedOutput()
+	t.Logf("%s", out)
+	return err
+}
+
+func TestSmokeScript(t *testing.T) {
+	err := runSmokeScript(t, http.StatusOK, "Hello World", &SmokeTest{Path: "/hello", Status: "200", Body: "Hello", Timeout: 5})
+	assert.NoError(t, err)
+
+	err = runSmokeScript(t, http.StatusOK, "Goodbye", &SmokeTest{Path: "/hello", Status: "200", Body: "Hello", Timeout: 2})
+	assert.Error(t, err)
+
+	err = runSmokeScript(t, http.StatusInternalServerError, "Hello", &SmokeTest{Path: "/hello", Status: "200", Timeout: 2})
+	assert.Error(t, err)
+}
+
+func TestSmoke(t *testing.T) {
+	arg := InitTest(t)
+	engine := useFakeEngine(t)
+	engine.Logs = "SEVERE: Context [/app] startup failed\n"
+
+	mc := new(arg)
+	test := &SmokeTest{Path: "/hello", Port: "8080", Status: "200", Timeout: 10}
+
+	cRuntime, err := cri.InitContainerRuntime()
+	assert.NoError(t, err)
+
+	if v, ok := cRuntime.(*critest.MockContainerManager); ok {
+		err = mc.Smoke(engine, "test-image:latest", test)
+		require.NoError(t, err)
+
+		// the loaded image runs with its own configuration
+		app := v.GetContainerByImage("test-image:latest")
+		assert.Empty(t, app.Opts.Cmd)
+		assert.Empty(t, app.Opts.Volumes)
+		probe := v.GetContainerByImage(MavenImage(*arg))
+		assert.Contains(t, probe.Opts.Script, "'http://10.88.0.7:8080/hello'")
+	}
+
+	engine.Address = ""
+	err = mc.Smoke(engine, "test-image:latest", test)
+	assert.ErrorContains(t, err, "has no network address")
+}
@@ -0,0 +1,235 @@
+package maven
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TestSummaryFile is the machine-readable test summary written below Folder.
+const TestSummaryFile = "target/test-summary.json"
+
+// ReportDirs are the report folders of the Surefire and Failsafe plugins below target/.
+var ReportDirs = []string{"surefire-reports", "failsafe-reports"}
+
+// TestSuite is a JUnit XML testsuite as written by Surefire and Failsafe.
+type TestSuite struct {
+	XMLName   xml.Name   `xml:"testsuite"`
+	Name      string     `xml:"name,attr"`
+	Package   string     `xml:"package,attr,omitempty"`
+	Tests     int        `xml:"tests,attr"`
+	Failures  int        `xml:"failures,attr"`
+	Errors    int        `xml:"errors,attr"`
+	Skipped   int        `xml:"skipped,attr"`
+	Time      string     `xml:"time,attr,omitempty"`
+	TestCases []TestCase `xml:"testcase"`
+
+	// Module is the reactor module folder relative to the project folder.
+	Module string `xml:"-"`
+	// Plugin is surefire or failsafe.
+	Plugin string `xml:"-"`
+}
+
+type TestCase struct {
+	Name      string       `xml:"name,attr"`
+	ClassName string       `xml:"classname,attr"`
+	Time      string       `xml:"time,attr,omitempty"`
+	Failure   *TestFailure `xml:"failure"`
+	Error     *TestFailure `xml:"error"`
+	Skipped   *TestFailure `xml:"skipped"`
+	SystemOut string       `xml:"system-out,omitempty"`
+	SystemErr string       `xml:"system-err,omitempty"`
+}
+
+type TestFailure struct {
+	Message string `xml:"message,attr,omitempty"`
+	Type    string `xml:"type,attr,omitempty"`
+	Text    string `xml:",chardata"`
+}
+
+// FailedTest is a failing or erroring test case of the summary.
+type FailedTest struct {
+	Module  string `json:"module"`
+	Class   string `json:"class"`
+	Name    string `json:"name"`
+	Message string `json:"message"`
+	Error   bool   `json:"error"`
+}
+
+// TestSummary aggregates the test results of all reactor modules.
+type TestSummary struct {
+	Tests    int          `json:"tests"`
+	Passed   int          `json:"passed"`
+	Failed   int          `json:"failed"`
+	Errors   int          `json:"errors"`
+	Skipped  int          `json:"skipped"`
+	Failures []FailedTest `json:"failures"`
+}
+
+// ReadTestReports collects the Surefire and Failsafe reports of every module
+// below folder. Reports older than since are left over from previous builds
+// and skipped, a zero since reads all reports.
+func ReadTestReports(folder string, since time.Time) ([]TestSuite, error) {
+	patterns := make([]string, len(ReportDirs))
+	for i, dir := range ReportDirs {
+		patterns[i] = dir + "/TEST-*.xml"
+	}
+	files, err := findTargetFiles(folder, since, patterns...)
+	if err != nil {
+		return nil, err
+	}
+
+	var suites []TestSuite
+	for _, file := range files {
+		data, err := os.ReadFile(file.Path)
+		if err != nil {
+			return nil, err
+		}
+		var suite TestSuite
+		if err := xml.Unmarshal(data, &suite); err != nil {
+			slog.Warn("Skipping unreadable test report", "file", file.Path, "error", err)
+			continue
+		}
+		suite.Module = file.Module
+		suite.Plugin = strings.TrimSuffix(path.Dir(file.Name), "-reports")
+		suites = append(suites, suite)
+	}
+	return suites, nil
+}
+
+// Summarize counts the test cases of the suites.
+func Summarize(suites []TestSuite) TestSummary {
+	summary := TestSummary{Failures: []FailedTest{}}
+	for _, suite := range suites {
+		for _, tc := range suite.TestCases {
+			summary.Tests++
+			switch {
+			case tc.Failure != nil:
+				summary.Failed++
+				summary.Failures = append(summary.Failures, failedTest(suite, tc, tc.Failure, false))
+			case tc.Error != nil:
+				summary.Errors++
+				summary.Failures = append(summary.Failures, failedTest(suite, tc, tc.Error, true))
+			case tc.Skipped != nil:
+				summary.Skipped++
+			default:
+				summary.Passed++
+			}
+		}
+	}
+	return summary
+}
+
+func failedTest(suite TestSuite, tc TestCase, failure *TestFailure, isError bool) FailedTest {
+	message := failure.Message
+	if message == "" {
+		message, _, _ = strings.Cut(strings.TrimSpace(failure.Text), "\n")
+	}
+	class := tc.ClassName
+	if class == "" {
+		class = suite.Name
+	}
+	return FailedTest{Module: suite.Module, Class: class, Name: tc.Name, Message: message, Error: isError}
+}
+
+// Log prints the summary and every failing test.
+func (s TestSummary) Log() {
+	for _, f := range s.Failures {
+		slog.Error("Test failed", "module", f.Module, "test", f.Class+"#"+f.Name, "message", f.Message, "error", f.Error)
+	}
+	slog.Info("Test summary", "tests", s.Tests, "passed", s.Passed, "failed", s.Failed, "errors", s.Errors, "skipped", s.Skipped)
+}
+
+// Write stores the summary as JSON in file.
+func (s TestSummary) Write(file string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(file, data, 0o644)
+}
+
+// TestSuites is a JUnit XML document merging the suites of all modules.
+type TestSuites struct {
+	XMLName  xml.Name    `xml:"testsuites"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Errors   int         `xml:"errors,attr"`
+	Skipped  int         `xml:"skipped,attr"`
+	Time     string      `xml:"time,attr"`
+	Suites   []TestSuite `xml:"testsuite"`
+}
+
+// MergeTestReports combines the suites into one document. The module of a
+// suite becomes its package and the prefix of its name, so suites of the same
+// class in different modules stay apart.
+func MergeTestReports(suites []TestSuite) TestSuites {
+	summary := Summarize(suites)
+	merged := TestSuites{
+		Tests:    summary.Tests,
+		Failures: summary.Failed,
+		Errors:   summary.Errors,
+		Skipped:  summary.Skipped,
+		Suites:   make([]TestSuite, 0, len(suites)),
+	}
+	var total float64
+	for _, suite := range suites {
+		if seconds, err := strconv.ParseFloat(suite.Time, 64); err == nil {
+			total += seconds
+		}
+		if suite.Module != "" && suite.Module != "." {
+			suite.Package = suite.Module
+			suite.Name = suite.Module + "." + suite.Name
+		}
+		merged.Suites = append(merged.Suites, suite)
+	}
+	merged.Time = strconv.FormatFloat(total, 'f', 3, 64)
+	return merged
+}
+
+// Write stores the merged report as JUnit XML in file.
+func (s TestSuites) Write(file string) error {
+	data, err := xml.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(file, append([]byte(xml.Header), data...), 0o644)
+}
+
+// ReportTests reads the test reports the build in folder wrote since start,
+// prints the summary and writes it to TestSummaryFile. With a junit file set,
+// relative to folder unless absolute, the reports are also merged into it.
+// It returns nil when no tests ran.
+func ReportTests(folder string, start time.Time, junit string) (*TestSummary, error) {
+	suites, err := ReadTestReports(folder, start)
+	if err != nil {
+		return nil, err
+	}
+	if len(suites) == 0 {
+		return nil, nil
+	}
+	summary := Summarize(suites)
+	summary.Log()
+	if junit != "" {
+		if !filepath.IsAbs(junit) {
+			junit = filepath.Join(folder, junit)
+		}
+		if err := MergeTestReports(suites).Write(junit); err != nil {
+			return &summary, err
+		}
+		slog.Info("Merged test reports", "file", junit, "suites", len(suites))
+	}
+	return &summary, summary.Write(filepath.Join(folder, TestSummaryFile))
+}
@@ -0,0 +1,153 @@
+package maven
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeReport(t *testing.T, dir, name, content string) string {
+	require.NoError(t, os.MkdirAll(dir, 0o755))
+	file := filepath.Join(dir, name)
+	require.NoError(t, os.WriteFile(file, []byte(content), 0o644))
+	return file
+}
+
+const surefireReport = `<?xml version="1.0" encoding="UTF-8"?>
+<testsuite name="com.example.ServiceTest" tests="4" failures="1" errors="1" skipped="1">
+  <testcase name="passes" classname="com.example.ServiceTest" time="0.01"/>
+  <testcase name="fails" classname="com.example.ServiceTest">
+    <failure message="expected: &lt;1&gt; but was: &lt;2&gt;" type="org.opentest4j.AssertionFailedError">stack</failure>
+  </testcase>
+  <testcase name="errors" classname="com.example.ServiceTest">
+    <error type="java.lang.IllegalStateException">java.lang.IllegalStateException: boom
+	at com.example.Service.run(Service.java:12)</error>
+  </testcase>
+  <testcase name="skipped" classname="com.example.ServiceTest"><skipped/></testcase>
+</testsuite>`
+
+const failsafeReport = `<testsuite name="com.example.ServiceIT" tests="1">
+  <testcase name="starts" classname="com.example.ServiceIT"/>
+</testsuite>`
+
+func TestReadTestReports(t *testing.T) {
+	folder := t.TempDir()
+	writeReport(t, filepath.Join(folder, "core/target/surefire-reports"), "TEST-com.example.ServiceTest.xml", surefireReport)
+	writeReport(t, filepath.Join(folder, "web/target/failsafe-reports"), "TEST-com.example.ServiceIT.xml", failsafeReport)
+	writeReport(t, filepath.Join(folder, "web/target/failsafe-reports"), "failsafe-summary.xml", `<failsafe-summary/>`)
+	writeReport(t, filepath.Join(folder, "web/target/surefire-reports"), "TEST-broken.xml", `<testsuite`)
+	writeReport(t, filepath.Join(folder, "src/test/resources/target/surefire-reports"), "TEST-fixture.xml", failsafeReport)
+
+	suites, err := ReadTestReports(folder, time.Time{})
+	require.NoError(t, err)
+	require.Len(t, suites, 2)
+
+	assert.Equal(t, "core", suites[0].Module)
+	assert.Equal(t, "surefire", suites[0].Plugin)
+	assert.Len(t, suites[0].TestCases, 4)
+	assert.Equal(t, "web", suites[1].Module)
+	assert.Equal(t, "failsafe", suites[1].Plugin)
+}
+
+func TestReadTestReportsSkipsStaleReports(t *testing.T) {
+	folder := t.TempDir()
+	stale := writeReport(t, filepath.Join(folder, "target/surefire-reports"), "TEST-Old.xml", failsafeReport)
+	start := time.Now()
+	require.NoError(t, os.Chtimes(stale, start.Add(-time.Hour), start.Add(-time.Hour)))
+	writeReport(t, filepath.Join(folder, "target/failsafe-reports"), "TEST-New.xml", failsafeReport)
+
+	suites, err := ReadTestReports(folder, start.Add(-time.Minute))
+	require.NoError(t, err)
+	require.Len(t, suites, 1)
+	assert.Equal(t, ".", suites[0].Module)
+	assert.Equal(t, "failsafe", suites[0].Plugin)
+}
+
+func TestSummarize(t *testing.T) {
+	folder := t.TempDir()
+	writeReport(t, filepath.Join(folder, "core/target/surefire-reports"), "TEST-com.example.ServiceTest.xml", surefireReport)
+	writeReport(t, filepath.Join(folder, "core/target/failsafe-reports"), "TEST-com.example.ServiceIT.xml", failsafeReport)
+	suites, err := ReadTestReports(folder, time.Time{})
+	require.NoError(t, err)
+
+	summary := Summarize(suites)
+	assert.Equal(t, 5, summary.Tests)
+	assert.Equal(t, 2, summary.Passed)
+	assert.Equal(t, 1, summary.Failed)
+	assert.Equal(t, 1, summary.Errors)
+	assert.Equal(t, 1, summary.Skipped)
+	assert.Equal(t, []FailedTest{
+		{Module: "core", Class: "com.example.ServiceTest", Name: "fails", Message: "expected: <1> but was: <2>"},
+		{Module: "core", Class: "com.example.ServiceTest", Name: "errors", Message: "java.lang.IllegalStateException: boom", Error: true},
+	}, summary.Failures)
+}
+
+func TestReportTests(t *testing.T) {
+	folder := t.TempDir()
+	summary, err := ReportTests(folder, time.Time{}, "")
+	require.NoError(t, err)
+	assert.Nil(t, summary)
+	assert.NoFileExists(t, filepath.Join(folder, TestSummaryFile))
+
+	writeReport(t, filepath.Join(folder, "target/surefire-reports"), "TEST-com.example.ServiceTest.xml", surefireReport)
+	summary, err = ReportTests(folder, time.Time{}, "")
+	require.NoError(t, err)
+	require.NotNil(t, summary)
+
+	data, err := os.ReadFile(filepath.Join(folder, TestSummaryFile))
+	require.NoError(t, err)
+	var written TestSummary
+	require.NoError(t, json.Unmarshal(data, &written))
+	assert.Equal(t, *summary, written)
+	assert.Equal(t, 4, written.Tests)
+	assert.Len(t, written.Failures, 2)
+}
+
+func TestMergeTestReports(t *testing.T) {
+	folder := t.TempDir()
+	writeReport(t, filepath.Join(folder, "target/surefire-reports"), "TEST-com.example.AppTest.xml",
+		`<testsuite name="com.example.AppTest" time="0.5" tests="1"><testcase name="runs" classname="com.example.AppTest"/></testsuite>`)
+	writeReport(t, filepath.Join(folder, "core/target/surefire-reports"), "TEST-com.example.ServiceTest.xml", surefireReport)
+	writeReport(t, filepath.Join(folder, "core/target/failsafe-reports"), "TEST-com.example.ServiceIT.xml", failsafeReport)
+
+	summary, err := ReportTests(folder, time.Time{}, "build/junit.xml")
+	require.NoError(t, err)
+	require.NotNil(t, summary)
+
+	data, err := os.ReadFile(filepath.Join(folder, "build/junit.xml"))
+	require.NoError(t, err)
+	var merged TestSuites
+	require.NoError(t, xml.Unmarshal(data, &merged))
+
+	assert.Equal(t, 6, merged.Tests)
+	assert.Equal(t, 1, merged.Failures)
+	assert.Equal(t, 1, merged.Errors)
+	assert.Equal(t, 1, merged.Skipped)
+	assert.Equal(t, "0.500", merged.Time)
+	require.Len(t, merged.Suites, 3)
+
+	names := map[string]string{}
+	for _, suite := range merged.Suites {
+		names[suite.Name] = suite.Package
+	}
+	assert.Equal(t, map[string]string{
+		"core.com.example.ServiceIT":   "core",
+		"core.com.example.ServiceTest": "core",
+		"com.example.AppTest":          "",
+	}, names)
+
+	for _, suite := range merged.Suites {
+		if suite.Name == "core.com.example.ServiceTest" {
+			require.Len(t, suite.TestCases, 4)
+			require.NotNil(t, suite.TestCases[1].Failure)
+			assert.Equal(t, "expected: <1> but was: <2>", suite.TestCases[1].Failure.Message)
+			assert.NotNil(t, suite.TestCases[3].Skipped)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package maven
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// ModuleTiming is the build result of a module from the reactor summary.
+type ModuleTiming struct {
+	Module   string
+	Status   string
+	Duration time.Duration
+}
+
+// reactorSummaryLine matches the lines of the reactor summary, modules that
+// were skipped have no duration. Names longer than the dotted column are
+// followed by the status directly.
+var reactorSummaryLine = regexp.MustCompile(`^\[INFO\] (.+?) (?:\.+ )?(SUCCESS|FAILURE|SKIPPED)(?: \[ *([0-9.:]+) (s|min|h)\])?\s*$`)
+
+// ParseReactorSummary reads the module timings from the reactor summary of
+// the mvn output. Builds of a single module print no summary.
+func ParseReactorSummary(r io.Reader) ([]ModuleTiming, error) {
+	var timings []ModuleTiming
+	inSummary := false
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "[INFO] Reactor Summary") {
+			// a later summary, e.g. of a retried build, replaces the previous one
+			inSummary, timings = true, nil
+			continue
+		}
+		if !inSummary {
+			continue
+		}
+		if strings.HasPrefix(line, "[INFO] ---") {
+			inSummary = false
+			continue
+		}
+		m := reactorSummaryLine.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		timing := ModuleTiming{Module: m[1], Status: m[2]}
+		if m[3] != "" {
+			d, err := parseMavenDuration(m[3], m[4])
+			if err != nil {
+				return nil, err
+			}
+			timing.Duration = d
+		}
+		timings = append(timings, timing)
+	}
+	return timings, scanner.Err()
+}
+
+// parseMavenDuration parses the durations Maven prints, 1.234 s, 01:05 min
+// and 1:01 h.
+func parseMavenDuration(value, unit string) (time.Duration, error) {
+	if unit == "s" {
+		seconds, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q: %w", value, err)
+		}
+		return time.Duration(seconds * float64(time.Second)), nil
+	}
+	major, minor, ok := strings.Cut(value, ":")
+	high, err1 := strconv.Atoi(major)
+	low, err2 := strconv.Atoi(minor)
+	if !ok || err1 != nil || err2 != nil {
+		return 0, fmt.Errorf("invalid duration %q %s", value, unit)
+	}
+	if unit == "h" {
+		return time.Duration(high)*time.Hour + time.Duration(low)*time.Minute, nil
+	}
+	return time.Duration(high)*time.Minute + time.Duration(low)*time.Second, nil
+}
+
+// ReadModuleTimings parses the reactor summary of the BuildLog in folder.
+func ReadModuleTimings(folder string) ([]ModuleTiming, error) {
+	fh, err := os.Open(filepath.Join(folder, BuildLog))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer fh.Close()
+	return ParseReactorSummary(fh)
+}
+
+// WriteModuleTimings prints the timings as a table in reactor order.
+func WriteModuleTimings(w io.Writer, timings []ModuleTiming) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "MODULE\tSTATUS\tTIME")
+	for _, t := range timings {
+		duration := "-"
+		if t.Status != "SKIPPED" {
+			duration = t.Duration.Round(time.Millisecond).String()
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", t.Module, t.Status, duration)
+	}
+	return tw.Flush()
+}
+
+// ReportModuleTimings prints the per-module timings of the last build in
+// folder and logs the slowest module.
+func ReportModuleTimings(w io.Writer, folder string) error {
+	timings, err := ReadModuleTimings(folder)
+	if err != nil || len(timings) == 0 {
+		return err
+	}
+	if err := WriteModuleTimings(w, timings); err != nil {
+		return err
+	}
+	slowest := timings[0]
+	for _, t := range timings[1:] {
+		if t.Duration > slowest.Duration {
+			slowest = t
+		}
+	}
+	slog.Info("Reactor build times", "modules", len(timings), "slowest", slowest.Module, "duration", slowest.Duration.Round(time.Millisecond))
+	return nil
+}
@@ -0,0 +1,61 @@
+package maven
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const reactorLog = `[INFO] Building app 1.0-SNAPSHOT                                   [3/3]
+[INFO] BUILD FAILURE
+[INFO] ------------------------------------------------------------------------
+[INFO] Reactor Summary for parent 1.0-SNAPSHOT:
+[INFO]
+[INFO] parent ............................................. SUCCESS [  0.412 s]
+[INFO] core ............................................... SUCCESS [01:05 min]
+[INFO] integration-tests-with-a-very-long-module-name-beyond-the-column SUCCESS [1:02 h]
+[INFO] web ................................................ FAILURE [  2.005 s]
+[INFO] app ................................................ SKIPPED
+[INFO] ------------------------------------------------------------------------
+[INFO] Total time:  01:02 h
+`
+
+func TestParseReactorSummary(t *testing.T) {
+	timings, err := ParseReactorSummary(strings.NewReader(reactorLog))
+	require.NoError(t, err)
+	assert.Equal(t, []ModuleTiming{
+		{Module: "parent", Status: "SUCCESS", Duration: 412 * time.Millisecond},
+		{Module: "core", Status: "SUCCESS", Duration: time.Minute + 5*time.Second},
+		{Module: "integration-tests-with-a-very-long-module-name-beyond-the-column", Status: "SUCCESS", Duration: time.Hour + 2*time.Minute},
+		{Module: "web", Status: "FAILURE", Duration: 2005 * time.Millisecond},
+		{Module: "app", Status: "SKIPPED"},
+	}, timings)
+
+	timings, err = ParseReactorSummary(strings.NewReader("[INFO] BUILD SUCCESS\n"))
+	require.NoError(t, err)
+	assert.Empty(t, timings)
+}
+
+func TestReportModuleTimings(t *testing.T) {
+	folder := t.TempDir()
+
+	var out bytes.Buffer
+	require.NoError(t, ReportModuleTimings(&out, folder))
+	assert.Empty(t, out.String())
+
+	require.NoError(t, os.MkdirAll(filepath.Join(folder, "target"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(folder, BuildLog), []byte(reactorLog), 0o644))
+	require.NoError(t, ReportModuleTimings(&out, folder))
+	assert.Equal(t, "MODULE                                                            STATUS   TIME\n"+
+		"parent                                                            SUCCESS  412ms\n"+
+		"core                                                              SUCCESS  1m5s\n"+
+		"integration-tests-with-a-very-long-module-name-beyond-the-column  SUCCESS  1h2m0s\n"+
+		"web                                                               FAILURE  2.005s\n"+
+		"app                                                               SKIPPED  -\n", out.String())
+}
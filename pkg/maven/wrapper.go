@@ -0,0 +1,172 @@
+package maven
+
+import (
+	"bufio"
+	"crypto/md5"
+	"fmt"
+	"math/big"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	WrapperScript     = "mvnw"
+	WrapperProperties = ".mvn/wrapper/maven-wrapper.properties"
+)
+
+// Wrapper describes the Maven wrapper of a project.
+type Wrapper struct {
+	DistributionURL       string
+	DistributionSha256Sum string
+	// Mirror is a repository in the Maven layout the distribution is
+	// downloaded from instead.
+	Mirror string
+	// OnlyScript is set for the mvnw of the only-script distribution type,
+	// which unpacks the distribution without the maven-wrapper.jar.
+	OnlyScript bool
+}
+
+// DetectWrapper returns the Maven wrapper of the project in folder or nil
+// when the project does not ship an mvnw script.
+func DetectWrapper(folder string) (*Wrapper, error) {
+	script, err := os.ReadFile(filepath.Join(folder, WrapperScript))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	props, err := readProperties(filepath.Join(folder, WrapperProperties))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	w := &Wrapper{
+		DistributionURL:       props["distributionUrl"],
+		DistributionSha256Sum: props["distributionSha256Sum"],
+		OnlyScript:            !strings.Contains(string(script), "maven-wrapper.jar"),
+	}
+	if w.DistributionSha256Sum != "" {
+		if _, err := w.distributionPath(); err != nil {
+			return nil, err
+		}
+	}
+	return w, nil
+}
+
+// readProperties parses the subset of the java properties format used by
+// maven-wrapper.properties: key=value lines, comments and escaped colons.
+func readProperties(file string) (map[string]string, error) {
+	fh, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer fh.Close()
+
+	props := map[string]string{}
+	scanner := bufio.NewScanner(fh)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "!") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		value = strings.ReplaceAll(strings.TrimSpace(value), `\:`, ":")
+		props[strings.TrimSpace(key)] = value
+	}
+	return props, scanner.Err()
+}
+
+// distributionPath returns the part of the distributionUrl below the
+// org/apache/maven folder. Only distributions published in that layout can be
+// fetched from a mirror.
+func (w *Wrapper) distributionPath() (string, error) {
+	_, suffix, ok := strings.Cut(w.DistributionURL, "/org/apache/maven/")
+	if !ok {
+		return "", fmt.Errorf("unsupported wrapper distributionUrl %q", w.DistributionURL)
+	}
+	return "org/apache/maven/" + suffix, nil
+}
+
+// distribution returns the file name of the distribution, its name without
+// the extension and the folder it unpacks to.
+func (w *Wrapper) distribution() (file, name, root string) {
+	file = path.Base(w.DistributionURL)
+	name = strings.TrimSuffix(strings.TrimSuffix(file, ".zip"), ".tar.gz")
+	return file, name, strings.TrimSuffix(name, "-bin")
+}
+
+// MavenVersion returns the Maven version of the distribution, empty when the
+// distributionUrl does not name an apache-maven distribution.
+func (w *Wrapper) MavenVersion() string {
+	_, _, root := w.distribution()
+	version, ok := strings.CutPrefix(root, "apache-maven-")
+	if !ok {
+		return ""
+	}
+	return version
+}
+
+// Home returns the folder below MAVEN_USER_HOME mvnw runs the distribution
+// from when it exists, so mvnw does not download it again. The script-only
+// wrapper keys it by a hash of the distributionUrl computed in shell, the
+// wrapper jar by the MD5 of it in base 36 with the distribution unpacked
+// below.
+func (w *Wrapper) Home() string {
+	_, name, root := w.distribution()
+	dists := path.Join(CacheLocation, "wrapper/dists")
+	if w.OnlyScript {
+		var h uint32
+		for _, b := range []byte(w.DistributionURL) {
+			h = h*31 + uint32(b)
+		}
+		return path.Join(dists, root, strconv.FormatUint(uint64(h), 16))
+	}
+	sum := md5.Sum([]byte(w.DistributionURL))
+	var hash big.Int
+	return path.Join(dists, name, hash.SetBytes(sum[:]).Text(36), root)
+}
+
+// Setup renders the shell lines preparing the wrapper. The wrapper keeps its
+// distributions in the cache mount and, when distributionSha256Sum is set,
+// the distribution is downloaded, verified and unpacked where mvnw looks for
+// it before mvnw runs.
+func (w *Wrapper) Setup() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "export MAVEN_USER_HOME=%s\n", shellQuote(strings.TrimSuffix(CacheLocation, "/")))
+	if w.DistributionSha256Sum == "" {
+		if w.Mirror != "" {
+			fmt.Fprintf(&sb, "export MVNW_REPOURL=%s\n", shellQuote(w.Mirror))
+		}
+		return sb.String()
+	}
+
+	source := w.DistributionURL
+	if w.Mirror != "" {
+		// DetectWrapper already rejected distributions that cannot be verified
+		suffix, _ := w.distributionPath()
+		source = strings.TrimSuffix(w.Mirror, "/") + "/" + suffix
+	}
+	file, _, root := w.distribution()
+	extract := "if command -v unzip >/dev/null; then unzip -q " + shellQuote(file) + "; else jar xf " + shellQuote(file) + "; fi"
+	if !strings.HasSuffix(file, ".zip") {
+		extract = "tar -xzf " + shellQuote(file)
+	}
+	home := w.Home()
+
+	fmt.Fprintf(&sb, "if [ ! -d %s ]; then\n", shellQuote(home))
+	sb.WriteString("  dist=$(mktemp -d)\n")
+	fmt.Fprintf(&sb, "  curl -fsSL -o \"$dist\"/%s %s\n", shellQuote(file), shellQuote(source))
+	fmt.Fprintf(&sb, "  (cd \"$dist\" && echo %s | sha256sum -c - && %s)\n", shellQuote(w.DistributionSha256Sum+"  "+file), extract)
+	fmt.Fprintf(&sb, "  chmod +x \"$dist\"/%s/bin/*\n", shellQuote(root))
+	fmt.Fprintf(&sb, "  mkdir -p %s && mv \"$dist\"/%s %s\n", shellQuote(path.Dir(home)), shellQuote(root), shellQuote(home))
+	sb.WriteString("  rm -rf \"$dist\"\n")
+	sb.WriteString("fi\n")
+	return sb.String()
+}
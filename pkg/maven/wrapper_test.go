@@ -0,0 +1,101 @@
+package maven
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeWrapper(t *testing.T, properties string) string {
+	folder := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(folder, WrapperScript), []byte("#!/bin/sh\n"), 0o755))
+	if properties != "" {
+		require.NoError(t, os.MkdirAll(filepath.Join(folder, ".mvn", "wrapper"), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(folder, WrapperProperties), []byte(properties), 0o644))
+	}
+	return folder
+}
+
+func TestDetectWrapperMissing(t *testing.T) {
+	w, err := DetectWrapper(t.TempDir())
+	assert.NoError(t, err)
+	assert.Nil(t, w)
+}
+
+func TestDetectWrapper(t *testing.T) {
+	folder := writeWrapper(t, `# Licensed to the Apache Software Foundation
+wrapperVersion=3.3.2
+distributionUrl=https\://repo.maven.apache.org/maven2/org/apache/maven/apache-maven/3.9.9/apache-maven-3.9.9-bin.zip
+`)
+
+	w, err := DetectWrapper(folder)
+	require.NoError(t, err)
+	assert.Equal(t, "https://repo.maven.apache.org/maven2/org/apache/maven/apache-maven/3.9.9/apache-maven-3.9.9-bin.zip", w.DistributionURL)
+	assert.Empty(t, w.DistributionSha256Sum)
+	assert.Equal(t, "3.9.9", w.MavenVersion())
+
+	bs := NewBuildScript(false, ".", "localhost")
+	bs.Wrapper = w
+	assert.Equal(t, "#!/bin/sh\nset -xe\ncd .\nexport MAVEN_USER_HOME=/root/.m2\nsh ./mvnw --batch-mode package\n", Script(bs))
+}
+
+func TestDetectWrapperVerified(t *testing.T) {
+	folder := writeWrapper(t, `distributionUrl=https://repo.maven.apache.org/maven2/org/apache/maven/apache-maven/3.9.9/apache-maven-3.9.9-bin.zip
+distributionSha256Sum=4ec3f26fb1a692473aea0235c300bd20f0f9fe741947c82c1234cefd76ac3a3c
+`)
+
+	w, err := DetectWrapper(folder)
+	require.NoError(t, err)
+
+	assert.True(t, w.OnlyScript)
+	home := "/root/.m2/wrapper/dists/apache-maven-3.9.9/3477a4f1"
+	assert.Equal(t, home, w.Home())
+	assert.Equal(t, "export MAVEN_USER_HOME=/root/.m2\n"+
+		"if [ ! -d "+home+" ]; then\n"+
+		"  dist=$(mktemp -d)\n"+
+		"  curl -fsSL -o \"$dist\"/apache-maven-3.9.9-bin.zip https://repo.maven.apache.org/maven2/org/apache/maven/apache-maven/3.9.9/apache-maven-3.9.9-bin.zip\n"+
+		"  (cd \"$dist\" && echo '4ec3f26fb1a692473aea0235c300bd20f0f9fe741947c82c1234cefd76ac3a3c  apache-maven-3.9.9-bin.zip' | sha256sum -c - && "+
+		"if command -v unzip >/dev/null; then unzip -q apache-maven-3.9.9-bin.zip; else jar xf apache-maven-3.9.9-bin.zip; fi)\n"+
+		"  chmod +x \"$dist\"/apache-maven-3.9.9/bin/*\n"+
+		"  mkdir -p /root/.m2/wrapper/dists/apache-maven-3.9.9 && mv \"$dist\"/apache-maven-3.9.9 "+home+"\n"+
+		"  rm -rf \"$dist\"\n"+
+		"fi\n", w.Setup())
+	assert.NotContains(t, w.Setup(), "MVNW_REPOURL")
+}
+
+func TestWrapperHomeJar(t *testing.T) {
+	folder := writeWrapper(t, "distributionUrl=https\\://repo.maven.apache.org/maven2/org/apache/maven/apache-maven/3.9.9/apache-maven-3.9.9-bin.zip\n")
+	require.NoError(t, os.WriteFile(filepath.Join(folder, WrapperScript), []byte("#!/bin/sh\nWRAPPER_JAR=\"$BASE_DIR/.mvn/wrapper/maven-wrapper.jar\"\n"), 0o755))
+
+	w, err := DetectWrapper(folder)
+	require.NoError(t, err)
+	assert.False(t, w.OnlyScript)
+	assert.Equal(t, "/root/.m2/wrapper/dists/apache-maven-3.9.9-bin/8yucwv6yw2jfm9nh8ttv439cc/apache-maven-3.9.9", w.Home())
+}
+
+func TestDetectWrapperUnsupportedURL(t *testing.T) {
+	folder := writeWrapper(t, `distributionUrl=https://example.com/maven.zip
+distributionSha256Sum=abc
+`)
+
+	_, err := DetectWrapper(folder)
+	assert.Error(t, err)
+
+	assert.Empty(t, (&Wrapper{DistributionURL: "https://example.com/maven.zip"}).MavenVersion())
+}
+
+func TestWrapperMirror(t *testing.T) {
+	w := &Wrapper{
+		DistributionURL: "https://repo.maven.apache.org/maven2/org/apache/maven/apache-maven/3.9.9/apache-maven-3.9.9-bin.zip",
+		Mirror:          "https://nexus.example.com/repository/maven-public",
+	}
+	assert.Equal(t, "export MAVEN_USER_HOME=/root/.m2\nexport MVNW_REPOURL=https://nexus.example.com/repository/maven-public\n", w.Setup())
+
+	w.DistributionSha256Sum = "4ec3f26fb1a692473aea0235c300bd20f0f9fe741947c82c1234cefd76ac3a3c"
+	assert.Contains(t, w.Setup(), "  curl -fsSL -o \"$dist\"/apache-maven-3.9.9-bin.zip "+
+		"https://nexus.example.com/repository/maven-public/org/apache/maven/apache-maven/3.9.9/apache-maven-3.9.9-bin.zip\n")
+	assert.NotContains(t, w.Setup(), "MVNW_REPOURL")
+}